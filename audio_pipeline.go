@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// การประมวลผลเสียงของบทหนึ่งใน ffmpeg ครั้งเดียว:
+// รวมไฟล์ส่วนย่อย -> ปรับปรุงเสียงของ engine -> ปรับความเร็ว -> normalize
+// -> เข้ารหัสครั้งเดียวตามโปรไฟล์
+type audioPipeline struct {
+	Speed   float64  // ความเร็วเสียง (1.0 = ไม่ปรับ)
+	Enhance []string // filter ของ engine ที่เป็น audioEnhancer (ว่าง = ไม่มี)
+}
+
+// normalize ครั้งเดียวของทั้ง chain หลังปรับความเร็ว (engine ไม่ต้องใส่ dynaudnorm เอง)
+const normalizeFilter = "dynaudnorm=p=0.9:s=5"
+
+// ขั้นตอนการประมวลผลเสียงของงานที่สร้างด้วย engine นี้
+func (c *Config) audioPipeline(engine Synthesizer) audioPipeline {
+	pipeline := audioPipeline{Speed: c.Speed}
+	if enhancer, ok := engine.(audioEnhancer); ok {
+		pipeline.Enhance = enhancer.EnhanceFilters(c.outputProfile().SampleRate)
+	}
+	return pipeline
+}
+
+// แยกความเร็วเป็นขั้นของ atempo ที่แต่ละขั้นอยู่ในช่วง 0.5 - 2.0
+// เช่น 3.0 = 2.0 * 1.5 ผลคูณของทุกขั้นเท่ากับ speed
+func atempoSteps(speed float64) []float64 {
+	var steps []float64
+	for speed > 2.0 {
+		steps = append(steps, 2.0)
+		speed /= 2.0
+	}
+	for speed < 0.5 {
+		steps = append(steps, 0.5)
+		speed /= 0.5
+	}
+	if speed != 1.0 {
+		steps = append(steps, speed)
+	}
+	return steps
+}
+
+// filter chain ของ -af จากการตั้งค่า
+// เช่น Speed 1.6 ของ Translate TTS = "volume=1.2,atempo=1.6,dynaudnorm=p=0.9:s=5"
+func (p audioPipeline) filter() string {
+	filters := append([]string{}, p.Enhance...)
+	for _, step := range atempoSteps(p.Speed) {
+		filters = append(filters, "atempo="+strconv.FormatFloat(step, 'g', 6, 64))
+	}
+	filters = append(filters, normalizeFilter)
+	return strings.Join(filters, ",")
+}
+
+// argument ของ ffmpeg ทั้งหมด: concat จากไฟล์รายการ, filter chain, encoder และ metadata
+func (p audioPipeline) ffmpegArgs(listFile, outputFile string, profile outputProfile, metadataArgs []string) []string {
+	args := []string{"-f", "concat", "-safe", "0", "-i", listFile, "-af", p.filter()}
+	args = append(args, profile.encodeArgs()...)
+	args = append(args, metadataArgs...)
+	return append(args, outputFile, "-y")
+}
+
+// รวมไฟล์เสียงส่วนย่อยใน tempDir เป็นไฟล์ output ด้วย ffmpeg ครั้งเดียว (เข้ารหัสครั้งเดียว)
+// metadataArgs คือ -metadata ของ ffmpeg สำหรับรูปแบบที่ไม่ใช้ ID3 tag (ว่างได้)
+func renderChapterAudio(ctx context.Context, tempDir, outputFile string, pipeline audioPipeline, profile outputProfile, metadataArgs []string) error {
+	// หาไฟล์ temp_part_*.* ใน tempDir (นามสกุลตามรูปแบบเสียงของ engine)
+	files, err := filepath.Glob(filepath.Join(tempDir, "temp_part_*.*"))
+	if err != nil || len(files) == 0 {
+		return fmt.Errorf("ไม่พบไฟล์เสียงใน %s", tempDir)
+	}
+
+	// เรียงลำดับไฟล์แบบ natural sorting (1, 2, 3, ..., 10, 11, 12 แทนที่จะเป็น 1, 10, 11, 12, 2, 3)
+	sortFilesNaturally(files)
+
+	// แสดงลำดับไฟล์ที่จะรวม
+	fmt.Println("📋 ลำดับไฟล์ที่จะรวม:")
+	for i, file := range files {
+		fmt.Printf("   %d. %s\n", i+1, filepath.Base(file))
+	}
+
+	// สร้างไฟล์รายการสำหรับ ffmpeg (ใช้ relative path จาก tempDir)
+	var filelistContent strings.Builder
+	for _, file := range files {
+		filelistContent.WriteString(concatListLine(filepath.Base(file)))
+	}
+	filelistPath := filepath.Join(tempDir, "filelist.txt")
+	err = os.WriteFile(filelistPath, []byte(filelistContent.String()), 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filelistPath)
+
+	// รันคำสั่ง ffmpeg จาก tempDir โดยใช้ absolute path สำหรับ output
+	absOutputFile, err := filepath.Abs(outputFile)
+	if err != nil {
+		return err
+	}
+	tempFile := tempOutputPath(absOutputFile)
+
+	if pipeline.Speed != 1.0 {
+		fmt.Printf("⚡ กำลังรวมไฟล์และปรับความเร็วเป็น %.1fx...\n", pipeline.Speed)
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", pipeline.ffmpegArgs("filelist.txt", tempFile, profile, metadataArgs)...)
+	cmd.Dir = tempDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("ffmpeg error: %v\nOutput: %s", err, string(output))
+	}
+
+	// แทนที่ไฟล์เดิมเมื่อเข้ารหัสเสร็จสมบูรณ์
+	err = os.Rename(tempFile, absOutputFile)
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("ไม่สามารถแทนที่ไฟล์ได้: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAtempoSteps(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  []float64
+	}{
+		{1.0, nil},
+		{1.6, []float64{1.6}},
+		{2.0, []float64{2.0}},
+		{3.0, []float64{2.0, 1.5}},
+		{4.0, []float64{2.0, 2.0}},
+		{0.25, []float64{0.5, 0.5}},
+	}
+	for _, tt := range tests {
+		got := atempoSteps(tt.speed)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("atempoSteps(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+		product := 1.0
+		for _, step := range got {
+			if step < 0.5 || step > 2.0 {
+				t.Errorf("atempoSteps(%v): step %v out of atempo range", tt.speed, step)
+			}
+			product *= step
+		}
+		if math.Abs(product-tt.speed) > 1e-9 {
+			t.Errorf("atempoSteps(%v): product = %v", tt.speed, product)
+		}
+	}
+}
+
+func TestAudioPipelineFilter(t *testing.T) {
+	translateEnhance := (&translateTTS{}).EnhanceFilters(48000)
+	cloudEnhance := (&cloudTTS{}).EnhanceFilters(48000)
+	tests := []struct {
+		speed   float64
+		enhance []string
+		want    string
+	}{
+		{1.0, nil, "dynaudnorm=p=0.9:s=5"},
+		{1.6, nil, "atempo=1.6,dynaudnorm=p=0.9:s=5"},
+		{1.0, translateEnhance, "volume=1.2,dynaudnorm=p=0.9:s=5"},
+		{1.6, translateEnhance, "volume=1.2,atempo=1.6,dynaudnorm=p=0.9:s=5"},
+		{3.0, translateEnhance, "volume=1.2,atempo=2,atempo=1.5,dynaudnorm=p=0.9:s=5"},
+		{4.0, translateEnhance, "volume=1.2,atempo=2,atempo=2,dynaudnorm=p=0.9:s=5"},
+		{1.0, cloudEnhance, "highpass=f=80,lowpass=f=15000,volume=1.1,dynaudnorm=p=0.9:s=5"},
+		{1.6, cloudEnhance, "highpass=f=80,lowpass=f=15000,volume=1.1,atempo=1.6,dynaudnorm=p=0.9:s=5"},
+		{3.0, cloudEnhance, "highpass=f=80,lowpass=f=15000,volume=1.1,atempo=2,atempo=1.5,dynaudnorm=p=0.9:s=5"},
+		{4.0, cloudEnhance, "highpass=f=80,lowpass=f=15000,volume=1.1,atempo=2,atempo=2,dynaudnorm=p=0.9:s=5"},
+	}
+	for _, tt := range tests {
+		pipeline := audioPipeline{Speed: tt.speed, Enhance: tt.enhance}
+		got := pipeline.filter()
+		if got != tt.want {
+			t.Errorf("speed %v, enhance %q:\n got %s\nwant %s", tt.speed, tt.enhance, got, tt.want)
+		}
+		// normalize ครั้งเดียวหลังปรับความเร็ว
+		if n := strings.Count(got, "dynaudnorm"); n != 1 || !strings.HasSuffix(got, normalizeFilter) {
+			t.Errorf("speed %v, enhance %q: %s normalizes %d times", tt.speed, tt.enhance, got, n)
+		}
+	}
+}
+
+func TestConfigAudioPipelineUsesEngineFilters(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Speed = 1.6
+	if got := cfg.audioPipeline(newTranslateTTS(&cfg)).filter(); got != "volume=1.2,atempo=1.6,dynaudnorm=p=0.9:s=5" {
+		t.Errorf("translate filter = %s", got)
+	}
+	// engine ที่ไม่ใช่ audioEnhancer ไม่เพิ่ม volume
+	if got := cfg.audioPipeline(passthroughEngine{}).filter(); got != "atempo=1.6,dynaudnorm=p=0.9:s=5" {
+		t.Errorf("plain engine filter = %s", got)
+	}
+}
+
+func TestCloudEnhanceLowpass(t *testing.T) {
+	// lowpass ต้องต่ำกว่าครึ่งหนึ่งของ sample rate
+	for rate, want := range map[int]string{48000: "lowpass=f=15000", 24000: "lowpass=f=10800"} {
+		if got := (&cloudTTS{}).EnhanceFilters(rate)[1]; got != want {
+			t.Errorf("EnhanceFilters(%d) lowpass = %s, want %s", rate, got, want)
+		}
+	}
+}
+
+func TestAudioPipelineFFmpegArgs(t *testing.T) {
+	profile := outputProfiles["opus-32k"]
+	pipeline := audioPipeline{Speed: 1.6, Enhance: (&translateTTS{}).EnhanceFilters(48000)}
+	got := pipeline.ffmpegArgs("filelist.txt", "out.opus", profile, []string{"-metadata", "title=บทที่ 1"})
+	want := []string{
+		"-f", "concat", "-safe", "0", "-i", "filelist.txt",
+		"-af", "volume=1.2,atempo=1.6,dynaudnorm=p=0.9:s=5",
+		"-c:a", "libopus", "-b:a", "32k", "-application", "voip", "-ar", "48000", "-ac", "1",
+		"-metadata", "title=บทที่ 1",
+		"out.opus", "-y",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ffmpegArgs =\n%q\nwant\n%q", got, want)
+	}
+}
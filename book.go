@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// บทหนึ่งในหนังสือเสียง (เวลาเป็นมิลลิวินาทีนับจากต้นเล่ม)
+type bookChapter struct {
+	Title string
+	Start int64
+	End   int64
+}
+
+// ชื่อหนังสือ ผู้แต่ง และรูปปกของเล่ม: ใช้ค่าจาก config ก่อน แล้วจึงใช้ข้อมูลจาก EPUB
+func (c *Config) bookMetadata(jobs []TTSJob) audioMetadata {
+	meta := audioMetadata{Album: c.BookTitle, Artist: c.Author, Cover: c.Cover}
+	for _, job := range jobs {
+		if job.Book == nil {
+			continue
+		}
+		if meta.Album == "" {
+			meta.Album = job.Book.Title
+		}
+		if meta.Artist == "" {
+			meta.Artist = job.Book.Author
+		}
+		if meta.Cover == "" {
+			meta.Cover = job.Book.CoverPath
+		}
+		break
+	}
+	return meta
+}
+
+// ชื่อบทในหนังสือเสียง: หัวข้อของบท หรือชื่อไฟล์หากไม่พบหัวข้อ
+func (job TTSJob) chapterName() string {
+	if job.Title != "" {
+		return job.Title
+	}
+	return strings.TrimSuffix(filepath.Base(job.OutputPath), filepath.Ext(job.OutputPath))
+}
+
+// ชื่อไฟล์ที่ปลอดภัยจากชื่อหนังสือ
+func bookFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "audiobook"
+	}
+	return name
+}
+
+// escape ค่าในไฟล์ FFMETADATA (=, ;, #, \ และขึ้นบรรทัดใหม่)
+func escapeFFMetadata(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", `\`+"\n").Replace(value)
+}
+
+// เนื้อหาไฟล์ FFMETADATA ของหนังสือพร้อม chapter
+func bookFFMetadata(meta audioMetadata, chapters []bookChapter) string {
+	var sb strings.Builder
+	sb.WriteString(";FFMETADATA1\n")
+	for _, tag := range [][2]string{{"title", meta.Album}, {"album", meta.Album}, {"artist", meta.Artist}, {"genre", "Audiobook"}} {
+		if tag[1] != "" {
+			fmt.Fprintf(&sb, "%s=%s\n", tag[0], escapeFFMetadata(tag[1]))
+		}
+	}
+	for _, ch := range chapters {
+		fmt.Fprintf(&sb, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n", ch.Start, ch.End, escapeFFMetadata(ch.Title))
+	}
+	return sb.String()
+}
+
+// บรรทัดของไฟล์รายการสำหรับ concat demuxer ของ ffmpeg
+func concatListLine(path string) string {
+	return "file '" + strings.ReplaceAll(path, "'", `'\''`) + "'\n"
+}
+
+// รวมไฟล์เสียงของทุกบทเป็นหนังสือเสียง AAC (.m4b) ไฟล์เดียว มี chapter ละหนึ่งงาน
+// แล้วอ่านรายการ chapter จากไฟล์ที่สร้างกลับมาตรวจสอบ
+func buildAudiobook(ctx context.Context, cfg *Config, jobs []TTSJob) (string, error) {
+	meta := cfg.bookMetadata(jobs)
+	if meta.Album == "" {
+		meta.Album = strings.TrimSuffix(filepath.Base(cfg.InputDir), filepath.Ext(cfg.InputDir))
+	}
+
+	var chapters []bookChapter
+	var list strings.Builder
+	var missing []string
+	var position int64
+	for _, job := range jobs {
+		path, err := filepath.Abs(job.OutputPath)
+		if err != nil {
+			return "", err
+		}
+		duration, err := probeDuration(path)
+		if err != nil {
+			missing = append(missing, filepath.Base(job.OutputPath))
+			continue
+		}
+		length := int64(duration * 1000)
+		chapters = append(chapters, bookChapter{Title: job.chapterName(), Start: position, End: position + length})
+		position += length
+		list.WriteString(concatListLine(path))
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("บทที่ยังไม่มีไฟล์เสียง: %s", strings.Join(missing, ", "))
+	}
+	if len(chapters) == 0 {
+		return "", fmt.Errorf("ไม่มีบทสำหรับหนังสือเสียง")
+	}
+
+	outputPath := filepath.Join(cfg.OutputDir, bookFileName(meta.Album)+".m4b")
+	listPath := filepath.Join(cfg.OutputDir, ".k-tts-book-list.txt")
+	metadataPath := filepath.Join(cfg.OutputDir, ".k-tts-book-metadata.txt")
+	defer os.Remove(listPath)
+	defer os.Remove(metadataPath)
+	err := os.WriteFile(listPath, []byte(list.String()), 0644)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(metadataPath, []byte(bookFFMetadata(meta, chapters)), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("📚 กำลังสร้างหนังสือเสียง %s (%d บท)...\n", filepath.Base(outputPath), len(chapters))
+	args := []string{"-f", "concat", "-safe", "0", "-i", listPath, "-i", metadataPath}
+	if meta.Cover != "" {
+		args = append(args, "-i", meta.Cover)
+	}
+	args = append(args, "-map", "0:a", "-map_metadata", "1", "-map_chapters", "1")
+	if meta.Cover != "" {
+		args = append(args, "-map", "2:v", "-c:v", "copy", "-disposition:v", "attached_pic")
+	}
+	tempPath := outputPath + ".tmp"
+	args = append(args,
+		"-c:a", "aac",
+		"-b:a", cfg.BookBitrate,
+		"-movflags", "+faststart",
+		"-f", "ipod",
+		tempPath, "-y")
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(tempPath)
+		return "", fmt.Errorf("ffmpeg error: %v\nOutput: %s", err, string(output))
+	}
+
+	// ตรวจสอบว่า chapter ในไฟล์ตรงกับบทที่ตั้งใจ
+	err = verifyBookChapters(tempPath, chapters)
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	err = os.Rename(tempPath, outputPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return outputPath, nil
+}
+
+// อ่านรายการ chapter ของไฟล์เสียงด้วย ffprobe
+func probeChapters(path string) ([]bookChapter, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_chapters", "-of", "json", path)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe error: %v", err)
+	}
+	var probe struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			EndTime   string            `json:"end_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	err = json.Unmarshal(output, &probe)
+	if err != nil {
+		return nil, fmt.Errorf("ผลลัพธ์ของ ffprobe ไม่ถูกต้อง: %v", err)
+	}
+	chapters := make([]bookChapter, len(probe.Chapters))
+	for i, ch := range probe.Chapters {
+		var start, end float64
+		fmt.Sscan(ch.StartTime, &start)
+		fmt.Sscan(ch.EndTime, &end)
+		chapters[i] = bookChapter{Title: ch.Tags["title"], Start: int64(start * 1000), End: int64(end * 1000)}
+	}
+	return chapters, nil
+}
+
+// เปรียบเทียบจำนวนและชื่อ chapter ที่อ่านกลับมากับที่ตั้งใจ
+func verifyBookChapters(path string, want []bookChapter) error {
+	got, err := probeChapters(path)
+	if err != nil {
+		return err
+	}
+	if len(got) != len(want) {
+		return fmt.Errorf("หนังสือเสียงมี %d chapter แต่ต้องมี %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Title != want[i].Title {
+			return fmt.Errorf("chapter %d ชื่อ %q แต่ต้องเป็น %q", i+1, got[i].Title, want[i].Title)
+		}
+	}
+	return nil
+}
+
+// สร้างหนังสือเสียงหลังประมวลผลทุกบท คืนค่า exit code
+func writeAudiobook(ctx context.Context, cfg *Config, jobs []TTSJob) int {
+	path, err := buildAudiobook(ctx, cfg, jobs)
+	if err != nil {
+		fmt.Printf("❌ ไม่สามารถสร้างหนังสือเสียง: %s\n", err.Error())
+		return 1
+	}
+	if info, err := os.Stat(path); err == nil {
+		fmt.Printf("📚 หนังสือเสียง: %s (%.1f MB)\n", path, float64(info.Size())/(1024*1024))
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestEscapeFFMetadata(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"บทที่ 1", "บทที่ 1"},
+		{"a=b", `a\=b`},
+		{"หนึ่ง; สอง", `หนึ่ง\; สอง`},
+		{"#1", `\#1`},
+		{`C:\book`, `C:\\book`},
+		{"บรรทัดแรก\nบรรทัดสอง", "บรรทัดแรก\\\nบรรทัดสอง"},
+	}
+	for _, tt := range tests {
+		if got := escapeFFMetadata(tt.in); got != tt.want {
+			t.Errorf("escapeFFMetadata(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBookFFMetadata(t *testing.T) {
+	meta := audioMetadata{Album: "เล่ม=1", Artist: "ผู้แต่ง"}
+	chapters := []bookChapter{{Title: "บทนำ", Start: 0, End: 1500}, {Title: "#2; จบ", Start: 1500, End: 3000}}
+	want := ";FFMETADATA1\n" +
+		"title=เล่ม\\=1\nalbum=เล่ม\\=1\nartist=ผู้แต่ง\ngenre=Audiobook\n" +
+		"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=1500\ntitle=บทนำ\n" +
+		"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=1500\nEND=3000\ntitle=\\#2\\; จบ\n"
+	if got := bookFFMetadata(meta, chapters); got != want {
+		t.Errorf("bookFFMetadata =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// เขียน FFMETADATA ลงไฟล์ m4b จริงด้วย ffmpeg แล้วอ่านกลับด้วย probeChapters
+func TestBookFFMetadataRoundTrip(t *testing.T) {
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("ไม่พบ %s", tool)
+		}
+	}
+
+	dir := t.TempDir()
+	chapters := []bookChapter{
+		{Title: "บทที่ 1: a=b", Start: 0, End: 1000},
+		{Title: "บทที่ 2; #ลับ", Start: 1000, End: 2500},
+		{Title: "บรรทัดแรก\nบรรทัดสอง", Start: 2500, End: 3000},
+		{Title: `ทาง\ลัด`, Start: 3000, End: 4000},
+	}
+	metadataPath := filepath.Join(dir, "metadata.txt")
+	err := os.WriteFile(metadataPath, []byte(bookFFMetadata(audioMetadata{Album: "เล่ม=ทดสอบ"}, chapters)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(dir, "book.m4b")
+	cmd := exec.Command("ffmpeg",
+		"-f", "lavfi", "-t", "4", "-i", "anullsrc=r=24000:cl=mono",
+		"-i", metadataPath,
+		"-map", "0:a", "-map_metadata", "1", "-map_chapters", "1",
+		"-c:a", "aac", "-f", "ipod", outputPath, "-y")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("ffmpeg: %v\n%s", err, output)
+	}
+
+	got, err := probeChapters(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(chapters) {
+		t.Fatalf("probeChapters = %+v, want %d chapters", got, len(chapters))
+	}
+	for i, want := range chapters {
+		if got[i].Title != want.Title {
+			t.Errorf("chapter %d title = %q, want %q", i+1, got[i].Title, want.Title)
+		}
+		if diff := got[i].Start - want.Start; diff < -20 || diff > 20 {
+			t.Errorf("chapter %d start = %dms, want %dms", i+1, got[i].Start, want.Start)
+		}
+	}
+	if err := verifyBookChapters(outputPath, chapters); err != nil {
+		t.Errorf("verifyBookChapters: %v", err)
+	}
+}
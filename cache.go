@@ -0,0 +1,316 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// เวอร์ชันของรูปแบบ key (เปลี่ยนเมื่อวิธีสร้าง key เปลี่ยน เพื่อไม่ให้ใช้ข้อมูลเก่าผิด)
+const chunkCacheVersion = "v1"
+
+// engine ที่มีการตั้งค่าเสียง (encoding, sample rate ฯลฯ) ที่ต้องรวมใน cache key
+type audioConfigDescriber interface {
+	AudioConfig() string
+}
+
+// engine ที่ใช้เพียงบางค่าของเสียง (เช่น Translate TTS ใช้แค่รหัสภาษา)
+// คืนเฉพาะค่าที่ใช้จริง เพื่อให้การเปลี่ยนชื่อเสียงหรือเพศไม่ทำให้ cache ใช้ไม่ได้
+type voiceKeyDescriber interface {
+	VoiceKey(voice VoiceSettings) []string
+}
+
+// cache ของเสียงแต่ละส่วน โดยใช้ hash ของ engine, เสียง, การตั้งค่าเสียง และข้อความเป็น key
+// เวลาแก้ไขไฟล์ (mtime) ใช้เป็นเวลาที่ใช้งานล่าสุดสำหรับการลบแบบ LRU
+type chunkCache struct {
+	dir     string
+	maxSize int64 // 0 = ไม่จำกัด
+
+	mu   sync.Mutex
+	size int64 // ขนาดรวมโดยประมาณ (-1 = ยังไม่ได้คำนวณ)
+}
+
+// ข้อมูลของไฟล์ใน cache
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func openChunkCache(dir string, maxSize int64) (*chunkCache, error) {
+	err := ensureDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ไม่สามารถสร้าง cache folder %s: %v", dir, err)
+	}
+	return &chunkCache{dir: dir, maxSize: maxSize, size: -1}, nil
+}
+
+// สร้าง key จากทุกค่าที่มีผลต่อเสียงที่ได้ (เฉพาะค่าของเสียงที่ engine ใช้จริง)
+func chunkCacheKey(engine Synthesizer, req SynthesisRequest) string {
+	audioConfig := ""
+	if d, ok := engine.(audioConfigDescriber); ok {
+		audioConfig = d.AudioConfig()
+	}
+	voice := []string{req.Voice.Name, req.Voice.Language, req.Voice.Gender}
+	if d, ok := engine.(voiceKeyDescriber); ok {
+		voice = d.VoiceKey(req.Voice)
+	}
+	fields := []string{chunkCacheVersion, engine.Name(), audioConfig}
+	fields = append(fields, voice...)
+	fields = append(fields, strconv.FormatBool(req.SSML), req.Text)
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// path ของไฟล์ใน cache (แยก folder ตาม 2 ตัวแรกของ key)
+func (c *chunkCache) path(key, format string) string {
+	return filepath.Join(c.dir, key[:2], key+"."+format)
+}
+
+// อ่านเสียงจาก cache และปรับเวลาใช้งานล่าสุด
+func (c *chunkCache) Get(key string) (*AudioChunk, bool) {
+	matches, err := filepath.Glob(filepath.Join(c.dir, key[:2], key+".*"))
+	if err != nil || len(matches) == 0 {
+		return nil, false
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(matches[0], now, now)
+
+	format := strings.TrimPrefix(filepath.Ext(matches[0]), ".")
+	return &AudioChunk{Data: data, Format: format}, true
+}
+
+// บันทึกเสียงลง cache แล้วลบไฟล์เก่าหากเกินขนาดที่กำหนด
+func (c *chunkCache) Put(key string, chunk *AudioChunk) error {
+	path := c.path(key, chunk.Format)
+	err := ensureDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	// เขียนไฟล์ชั่วคราวก่อนแล้วค่อย rename เพื่อไม่ให้ worker อื่นอ่านไฟล์ที่เขียนไม่เสร็จ
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "tmp-"+key+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Write(chunk.Data)
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size >= 0 {
+		c.size += int64(len(chunk.Data))
+	}
+	if c.maxSize > 0 && (c.size < 0 || c.size > c.maxSize) {
+		c.evictLocked()
+	}
+	return nil
+}
+
+// รายการไฟล์ทั้งหมดใน cache
+func (c *chunkCache) entries() ([]cacheEntry, error) {
+	var entries []cacheEntry
+	err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), "tmp-") {
+			return nil
+		}
+		entries = append(entries, cacheEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return entries, err
+}
+
+// ลบไฟล์ที่ใช้งานล่าสุดนานที่สุดจนขนาดรวมไม่เกิน maxSize
+func (c *chunkCache) evictLocked() {
+	entries, err := c.entries()
+	if err != nil {
+		return
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if os.Remove(e.path) == nil {
+			total -= e.size
+		}
+	}
+	c.size = total
+}
+
+// ลบไฟล์ที่ไม่ได้ใช้งานนานกว่า age
+func (c *chunkCache) prune(age time.Duration) (int, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	cutoff := time.Now().Add(-age)
+	removed := 0
+	var freed int64
+	for _, e := range entries {
+		if e.modTime.Before(cutoff) && os.Remove(e.path) == nil {
+			removed++
+			freed += e.size
+		}
+	}
+	c.size = -1
+	return removed, freed, nil
+}
+
+// แปลงขนาดเช่น "500MB", "2GB" เป็นจำนวน bytes ("0" = ไม่จำกัด)
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), 64)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("ขนาดไม่ถูกต้อง: %q", s)
+			}
+			return int64(value * float64(u.factor)), nil
+		}
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("ขนาดไม่ถูกต้อง: %q", s)
+	}
+	return value, nil
+}
+
+// แปลงช่วงเวลาที่รองรับหน่วยวัน เช่น "30d", "12h"
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("ช่วงเวลาไม่ถูกต้อง: %q", s)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ค่าเริ่มต้นของ cache folder
+func defaultCacheDir() string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return ".k-tts-cache"
+	}
+	return filepath.Join(base, "k-tts", "chunks")
+}
+
+// คำสั่ง cache: stats และ prune
+func runCacheCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("ใช้งาน: k-tts cache stats | k-tts cache prune --older-than 30d")
+	}
+
+	cfg, err := loadConfig(nil)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("k-tts cache "+args[0], flag.ContinueOnError)
+	dir := fs.String("dir", cfg.CacheDir, "cache folder")
+	olderThan := fs.String("older-than", "", "ลบไฟล์ที่ไม่ได้ใช้งานนานกว่านี้ เช่น 30d, 12h")
+	err = fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	maxSize, err := parseByteSize(cfg.CacheMaxSize)
+	if err != nil {
+		return err
+	}
+	cache, err := openChunkCache(*dir, maxSize)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "stats":
+		entries, err := cache.entries()
+		if err != nil {
+			return err
+		}
+		var total int64
+		var oldest, newest time.Time
+		for _, e := range entries {
+			total += e.size
+			if oldest.IsZero() || e.modTime.Before(oldest) {
+				oldest = e.modTime
+			}
+			if e.modTime.After(newest) {
+				newest = e.modTime
+			}
+		}
+		fmt.Printf("📦 Cache: %s\n", cache.dir)
+		fmt.Printf("   จำนวนไฟล์: %d\n", len(entries))
+		fmt.Printf("   ขนาดรวม: %.1f MB", float64(total)/(1024*1024))
+		if maxSize > 0 {
+			fmt.Printf(" / %.1f MB (%.0f%%)", float64(maxSize)/(1024*1024), float64(total)*100/float64(maxSize))
+		}
+		fmt.Println()
+		if len(entries) > 0 {
+			fmt.Printf("   ใช้งานเก่าสุด: %s\n", oldest.Format("2006-01-02 15:04"))
+			fmt.Printf("   ใช้งานล่าสุด: %s\n", newest.Format("2006-01-02 15:04"))
+		}
+		return nil
+
+	case "prune":
+		if *olderThan == "" {
+			return fmt.Errorf("ต้องระบุ --older-than เช่น 30d")
+		}
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		removed, freed, err := cache.prune(age)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🧹 ลบไฟล์ %d ไฟล์ (%.1f MB)\n", removed, float64(freed)/(1024*1024))
+		return nil
+	}
+
+	return fmt.Errorf("ไม่รู้จักคำสั่ง cache %q", args[0])
+}
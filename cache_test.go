@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChunkCacheKeyVoiceInputs(t *testing.T) {
+	cfg := defaultConfig()
+	translate := newTranslateTTS(&cfg)
+	cloud := &cloudTTS{profile: outputProfiles[defaultOutputProfile]}
+	base := SynthesisRequest{Text: "สวัสดี", Voice: VoiceSettings{Name: "th-TH-Neural2-C", Language: "th-TH", Gender: "FEMALE"}}
+
+	with := func(change func(*SynthesisRequest)) SynthesisRequest {
+		req := base
+		change(&req)
+		return req
+	}
+	otherName := with(func(r *SynthesisRequest) { r.Voice.Name = "th-TH-Standard-A" })
+	otherGender := with(func(r *SynthesisRequest) { r.Voice.Gender = "MALE" })
+	otherRegion := with(func(r *SynthesisRequest) { r.Voice.Language = "th" })
+	otherLanguage := with(func(r *SynthesisRequest) { r.Voice.Language = "en-US" })
+	otherText := with(func(r *SynthesisRequest) { r.Text = "ลาก่อน" })
+	ssml := with(func(r *SynthesisRequest) { r.SSML = true })
+
+	tests := []struct {
+		name   string
+		engine Synthesizer
+		req    SynthesisRequest
+		same   bool
+	}{
+		// Translate TTS ใช้แค่รหัสภาษา
+		{"translate name", translate, otherName, true},
+		{"translate gender", translate, otherGender, true},
+		{"translate region", translate, otherRegion, true},
+		{"translate language", translate, otherLanguage, false},
+		{"translate text", translate, otherText, false},
+		// Cloud TTS ใช้ทุกค่าของเสียง
+		{"cloud name", cloud, otherName, false},
+		{"cloud gender", cloud, otherGender, false},
+		{"cloud region", cloud, otherRegion, false},
+		{"cloud ssml", cloud, ssml, false},
+		// engine ที่ไม่ระบุถือว่าใช้ทุกค่า
+		{"other name", passthroughEngine{}, otherName, false},
+	}
+	for _, tt := range tests {
+		same := chunkCacheKey(tt.engine, base) == chunkCacheKey(tt.engine, tt.req)
+		if same != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, same, tt.same)
+		}
+	}
+	if chunkCacheKey(translate, base) == chunkCacheKey(cloud, base) {
+		t.Error("translate and cloud share a key")
+	}
+}
+
+func TestChunkCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := openChunkCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &AudioChunk{Data: bytes.Repeat([]byte{1}, 100), Format: "mp3"}
+	keys := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		keys[name] = chunkCacheKey(passthroughEngine{}, SynthesisRequest{Text: name})
+	}
+
+	// a เก่าสุด แต่ถูกอ่านล่าสุดจึงกลายเป็นใหม่สุด
+	start := time.Now().Add(-time.Hour)
+	for i, name := range []string{"a", "b", "c"} {
+		if err := cache.Put(keys[name], chunk); err != nil {
+			t.Fatal(err)
+		}
+		modTime := start.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(cache.path(keys[name], "mp3"), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := cache.Get(keys["a"]); !ok {
+		t.Fatal("Get(a) missed")
+	}
+
+	cache.maxSize = 250
+	if err := cache.Put(keys["d"], chunk); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true} {
+		if _, ok := cache.Get(keys[name]); ok != want {
+			t.Errorf("after eviction %s cached = %v, want %v", name, ok, want)
+		}
+	}
+	if cache.size != 200 {
+		t.Errorf("size = %d, want 200", cache.size)
+	}
+}
+
+func TestChunkCachePrune(t *testing.T) {
+	cache, err := openChunkCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &AudioChunk{Data: []byte("audio"), Format: "wav"}
+	oldKey := chunkCacheKey(passthroughEngine{}, SynthesisRequest{Text: "old"})
+	newKey := chunkCacheKey(passthroughEngine{}, SynthesisRequest{Text: "new"})
+	for _, key := range []string{oldKey, newKey} {
+		if err := cache.Put(key, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(cache.path(oldKey, "wav"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, freed, err := cache.prune(24 * time.Hour)
+	if err != nil || removed != 1 || freed != 5 {
+		t.Errorf("prune = %d, %d, %v, want 1 file of 5 bytes", removed, freed, err)
+	}
+	if got, ok := cache.Get(newKey); !ok || got.Format != "wav" {
+		t.Errorf("Get(new) = %+v, %v, want the wav chunk", got, ok)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(cache.dir, oldKey[:2], oldKey+".*")); len(matches) != 0 {
+		t.Errorf("old chunk still cached: %v", matches)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1024", 1024, false},
+		{"512B", 512, false},
+		{"2KB", 2 << 10, false},
+		{"500MB", 500 << 20, false},
+		{" 1.5gb ", 3 << 29, false},
+		{"1TB", 1 << 40, false},
+		{"MB", 0, true},
+		{"-1GB", 0, true},
+		{"-5", 0, true},
+		{"10XB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"0.5d", 12 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"d", 0, true},
+		{"week", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ประเภทของกฎทำความสะอาดข้อความ
+const (
+	cleanActionReplace    = "replace"     // แทนที่ข้อความที่ตรงกับ pattern ด้วย replace (รองรับ $1)
+	cleanActionDeleteLine = "delete_line" // ลบบรรทัดที่ตรงกับ pattern ทั้งบรรทัด
+	cleanActionMap        = "map"         // แทนที่สัญลักษณ์ด้วยคำอ่าน เช่น "&" เป็น "และ"
+	cleanActionNormalize  = "normalize"   // อ่านตัวเลข วันที่ เวลา และหน่วยเป็นคำ
+)
+
+// กฎทำความสะอาดข้อความหนึ่งข้อ (ทำตามลำดับทีละบรรทัด)
+type CleanRule struct {
+	Name    string            `json:"name,omitempty"`
+	Action  string            `json:"action"`
+	Pattern string            `json:"pattern,omitempty"`
+	Replace string            `json:"replace,omitempty"`
+	Map     map[string]string `json:"map,omitempty"`
+}
+
+// อักขระพิเศษที่ไม่ต้องการให้อ่าน
+const specialCharsPattern = `[#*_~` + "`" + `^|\\/\[\]{}<>@$%&+=§¶†‡•…]`
+
+// ลบหมายเลขบทที่อยู่ในบรรทัดเดี่ยว (เช่น "1" "## 2" "บทที่ 1" "Chapter 1")
+var deleteChapterHeadingRule = CleanRule{
+	Name:    "chapter-heading",
+	Action:  cleanActionDeleteLine,
+	Pattern: `^[#*\s]*(\d+|บทที่\s*\d+|(?i:chapter)\s*\d+)[#*\s]*$`,
+}
+
+// ชุดกฎสำเร็จรูป
+var cleanPresets = map[string][]CleanRule{
+	// นิยาย: ลบหมายเลขบท อ่านตัวเลขเป็นคำ แล้วลบสัญลักษณ์ทั้งหมด
+	"novel": {
+		deleteChapterHeadingRule,
+		{Name: "normalize", Action: cleanActionNormalize},
+		{Name: "special-chars", Action: cleanActionReplace, Pattern: specialCharsPattern, Replace: " "},
+	},
+	// ข่าว: อ่านสัญลักษณ์ที่มีความหมาย ข้าม URL และอีเมล
+	"news": {
+		deleteChapterHeadingRule,
+		{Name: "url", Action: cleanActionReplace, Pattern: `https?://\S+|www\.\S+`, Replace: " "},
+		{Name: "email", Action: cleanActionReplace, Pattern: `[\w.+-]+@[\w-]+(\.[\w-]+)+`, Replace: " "},
+		{Name: "normalize", Action: cleanActionNormalize},
+		{Name: "symbols", Action: cleanActionMap, Map: map[string]string{"&": "และ", "+": "บวก", "@": "แอท"}},
+		{Name: "special-chars", Action: cleanActionReplace, Pattern: specialCharsPattern, Replace: " "},
+	},
+	// เอกสารเทคนิค: อ่านสัญลักษณ์ทางคณิตศาสตร์และโปรแกรมเป็นคำ
+	"technical": {
+		deleteChapterHeadingRule,
+		{Name: "markdown-heading", Action: cleanActionReplace, Pattern: `^\s*#+\s+`, Replace: ""},
+		{Name: "normalize", Action: cleanActionNormalize},
+		{Name: "symbols", Action: cleanActionMap, Map: map[string]string{
+			"==": "เท่ากับเท่ากับ", "!=": "ไม่เท่ากับ", "<=": "น้อยกว่าหรือเท่ากับ", ">=": "มากกว่าหรือเท่ากับ",
+			"->": "ไปยัง", "=>": "ไปยัง", "&&": "และ", "||": "หรือ",
+			"+": "บวก", "=": "เท่ากับ", "<": "น้อยกว่า", ">": "มากกว่า", "&": "และ", "@": "แอท",
+			"/": "ทับ", "%": "เปอร์เซ็นต์", "#": "ชาร์ป", "_": "อันเดอร์สกอร์", "*": "คูณ",
+		}},
+		{Name: "special-chars", Action: cleanActionReplace, Pattern: specialCharsPattern, Replace: " "},
+	},
+}
+
+// รายชื่อชุดกฎสำเร็จรูปทั้งหมด
+func cleanPresetNames() []string {
+	var names []string
+	for name := range cleanPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// กฎที่คอมไพล์แล้ว
+type compiledCleanRule struct {
+	rule     CleanRule
+	pattern  *regexp.Regexp
+	replacer *strings.Replacer
+}
+
+// ชุดกฎทำความสะอาดข้อความที่คอมไพล์ครั้งเดียวแล้วใช้ได้ทุกบท
+type textCleaner struct {
+	rules []compiledCleanRule
+}
+
+// คอมไพล์ชุดกฎสำเร็จรูปตามด้วยกฎเพิ่มเติมจาก config
+func newTextCleaner(preset string, extra []CleanRule) (*textCleaner, error) {
+	var rules []CleanRule
+	if preset != "" && preset != "none" {
+		presetRules, ok := cleanPresets[preset]
+		if !ok {
+			return nil, fmt.Errorf("ไม่รู้จักชุดกฎ %q (รองรับ: %s, none)", preset, strings.Join(cleanPresetNames(), ", "))
+		}
+		rules = append(rules, presetRules...)
+	}
+	presetCount := len(rules)
+	rules = append(rules, extra...)
+
+	cleaner := &textCleaner{}
+	for i, rule := range rules {
+		name := rule.Name
+		if i >= presetCount {
+			name = strings.TrimSpace(fmt.Sprintf("clean_rules[%d] %s", i-presetCount, rule.Name))
+		}
+		compiled := compiledCleanRule{rule: rule}
+		switch rule.Action {
+		case cleanActionReplace, cleanActionDeleteLine:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("กฎ %s: pattern ไม่ถูกต้อง: %v", name, err)
+			}
+			compiled.pattern = re
+		case cleanActionMap:
+			if len(rule.Map) == 0 {
+				return nil, fmt.Errorf("กฎ %s: ต้องระบุ map", name)
+			}
+			// สัญลักษณ์ที่ยาวกว่าต้องมาก่อน เช่น "==" ก่อน "="
+			var pairs []string
+			for _, symbol := range sortedByLength(rule.Map) {
+				pairs = append(pairs, symbol, " "+rule.Map[symbol]+" ")
+			}
+			compiled.replacer = strings.NewReplacer(pairs...)
+		case cleanActionNormalize:
+		default:
+			return nil, fmt.Errorf("กฎ %s: ไม่รู้จัก action %q (รองรับ: replace, delete_line, map, normalize)", name, rule.Action)
+		}
+		cleaner.rules = append(cleaner.rules, compiled)
+	}
+	return cleaner, nil
+}
+
+// key ของตารางเรียงจากยาวไปสั้น
+func sortedByLength(table map[string]string) []string {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// ทำความสะอาดบรรทัดเดียว คืน false หากบรรทัดถูกลบ
+func (c *textCleaner) cleanLine(line string) (string, bool) {
+	for _, r := range c.rules {
+		switch r.rule.Action {
+		case cleanActionDeleteLine:
+			if r.pattern.MatchString(strings.TrimSpace(line)) {
+				return "", false
+			}
+		case cleanActionReplace:
+			line = r.pattern.ReplaceAllString(line, r.rule.Replace)
+		case cleanActionMap:
+			line = r.replacer.Replace(line)
+		case cleanActionNormalize:
+			line = normalizeThaiText(line)
+		}
+	}
+	// ลบช่องว่างที่ซ้ำซ้อน
+	line = strings.Join(strings.Fields(line), " ")
+	return line, line != ""
+}
+
+// ทำความสะอาดข้อความทั้งบท คืนย่อหน้าละบรรทัด
+func (c *textCleaner) clean(text string) string {
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		if paragraph, ok := c.cleanLine(line); ok {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// คำสั่ง clean: แสดงข้อความหลังทำความสะอาด หรือความแตกต่างกับต้นฉบับ (--show)
+func runCleanCommand(args []string) error {
+	cfg, err := loadConfig(nil)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("k-tts clean", flag.ContinueOnError)
+	show := fs.Bool("show", false, "แสดงความแตกต่างระหว่างข้อความต้นฉบับและข้อความที่ทำความสะอาดแล้ว")
+	input := fs.String("input", cfg.InputDir, "folder ไฟล์ข้อความต้นฉบับ")
+	preset := fs.String("clean-preset", cfg.CleanPreset, "ชุดกฎทำความสะอาดข้อความ")
+	only := fs.String("only", "", "เลือกเฉพาะบท เช่น 012-020,025")
+	encoding := fs.String("encoding", cfg.Encoding, "การเข้ารหัสของไฟล์ข้อความ")
+	err = fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cleaner, err := newTextCleaner(*preset, cfg.CleanRules)
+	if err != nil {
+		return err
+	}
+	selector, err := parseChapterSelector(*only)
+	if err != nil {
+		return err
+	}
+	files, err := findInputFiles(*input, cfg.Glob)
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	id := 0
+	for _, file := range files {
+		chapters, err := readInputChapters(cfg, file, *encoding)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		for _, chapter := range chapters {
+			id++
+			if chapter.SSML || !selector.matches(TTSJob{ID: id, FilePath: chapter.FilePath}) {
+				continue
+			}
+			showCleaned(cleaner, chapter, *preset, *show)
+		}
+	}
+	return nil
+}
+
+// แสดงข้อความของบทหลังทำความสะอาด หรือบรรทัดที่เปลี่ยน (show)
+func showCleaned(cleaner *textCleaner, chapter inputChapter, preset string, show bool) {
+	if !show {
+		fmt.Printf("==> %s <==\n%s\n\n", filepath.Base(chapter.FilePath), stripEmphasis(cleaner.clean(chapter.Text)))
+		return
+	}
+
+	fmt.Printf("--- %s\n+++ %s (%s)\n", chapter.FilePath, chapter.FilePath, preset)
+	changed := 0
+	for n, line := range strings.Split(stripEmphasis(chapter.Text), "\n") {
+		raw := strings.TrimRight(line, "\r")
+		cleaned, kept := cleaner.cleanLine(raw)
+		if strings.TrimSpace(raw) == "" || (kept && cleaned == raw) {
+			continue
+		}
+		changed++
+		fmt.Printf("@@ บรรทัด %d @@\n-%s\n", n+1, raw)
+		if kept {
+			fmt.Printf("+%s\n", cleaned)
+		}
+	}
+	if changed == 0 {
+		fmt.Println("(ไม่มีการเปลี่ยนแปลง)")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// ทำความสะอาดข้อความด้วยชุดกฎที่กำหนด
+func cleanWith(t *testing.T, preset string, extra []CleanRule, text string) string {
+	t.Helper()
+	cleaner, err := newTextCleaner(preset, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cleaner.clean(text)
+}
+
+func TestCleanRuleActions(t *testing.T) {
+	tests := []struct {
+		name string
+		rule CleanRule
+		in   string
+		want string
+	}{
+		{"replace", CleanRule{Action: cleanActionReplace, Pattern: `\(([^)]*)\)`, Replace: "$1"}, "ชี่ (พลัง) ไหล", "ชี่ พลัง ไหล"},
+		{"replace to nothing", CleanRule{Action: cleanActionReplace, Pattern: `\[\d+\]`}, "ดาบ[1] และ[2]กระบี่", "ดาบ และกระบี่"},
+		// บรรทัดที่ตรง pattern หายไปทั้งบรรทัด ช่องว่างหัวท้ายไม่มีผล
+		{"delete line", CleanRule{Action: cleanActionDeleteLine, Pattern: `^\*+$`}, "ก่อน\n  ***  \nหลัง", "ก่อน\nหลัง"},
+		// สัญลักษณ์ที่ยาวกว่ามาก่อน
+		{"map", CleanRule{Action: cleanActionMap, Map: map[string]string{"=": "เท่ากับ", "==": "เท่ากับเท่ากับ"}}, "a==b=c", "a เท่ากับเท่ากับ b เท่ากับ c"},
+		{"normalize", CleanRule{Action: cleanActionNormalize}, "ราคา 12 บาท", "ราคา สิบสอง บาท"},
+	}
+	for _, tt := range tests {
+		if got := cleanWith(t, "none", []CleanRule{tt.rule}, tt.in); got != tt.want {
+			t.Errorf("%s: clean(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanRulesRunInOrder(t *testing.T) {
+	// กฎเพิ่มเติมทำหลังชุดกฎสำเร็จรูป จึงเห็นข้อความที่ normalize แล้ว
+	extra := []CleanRule{{Action: cleanActionReplace, Pattern: `สิบสอง`, Replace: "โหล"}}
+	if got := cleanWith(t, "novel", extra, "ไข่ 12 ฟอง"); got != "ไข่ โหล ฟอง" {
+		t.Errorf("novel + extra = %q, want %q", got, "ไข่ โหล ฟอง")
+	}
+
+	// ลำดับของกฎเพิ่มเติมตามที่เขียน
+	rules := []CleanRule{
+		{Action: cleanActionReplace, Pattern: `a`, Replace: "b"},
+		{Action: cleanActionReplace, Pattern: `b`, Replace: "c"},
+	}
+	if got := cleanWith(t, "", rules, "ab"); got != "cc" {
+		t.Errorf("ordered rules = %q, want %q", got, "cc")
+	}
+}
+
+func TestCleanPresets(t *testing.T) {
+	text := "บทที่ 3\n## Chapter 4\nชี่ & พลัง + 5% ส่ง a@b.com ดู https://example.com/x\nx == y -> z"
+	tests := []struct {
+		preset string
+		want   string
+	}{
+		// หมายเลขบทถูกลบ ตัวเลขอ่านเป็นคำ สัญลักษณ์ถูกลบ
+		{"novel", "ชี่ พลัง ห้าเปอร์เซ็นต์ ส่ง a b.com ดู https: example.com x\nx y - z"},
+		// URL และอีเมลถูกข้าม สัญลักษณ์ที่มีความหมายอ่านเป็นคำ
+		{"news", "ชี่ และ พลัง บวก ห้าเปอร์เซ็นต์ ส่ง ดู\nx y - z"},
+		{"technical", "ชี่ และ พลัง บวก ห้าเปอร์เซ็นต์ ส่ง a แอท b.com ดู https: ทับ ทับ example.com ทับ x\nx เท่ากับเท่ากับ y ไปยัง z"},
+		{"none", "บทที่ 3\n## Chapter 4\nชี่ & พลัง + 5% ส่ง a@b.com ดู https://example.com/x\nx == y -> z"},
+	}
+	for _, tt := range tests {
+		if got := cleanWith(t, tt.preset, nil, text); got != tt.want {
+			t.Errorf("preset %s:\n got %q\nwant %q", tt.preset, got, tt.want)
+		}
+	}
+
+	// ชุดกฎว่างเท่ากับ none
+	if got, want := cleanWith(t, "", nil, text), cleanWith(t, "none", nil, text); got != want {
+		t.Errorf("empty preset = %q, want %q", got, want)
+	}
+}
+
+func TestNewTextCleanerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		preset  string
+		rules   []CleanRule
+		wantErr string
+	}{
+		{"unknown preset", "poem", nil, `ไม่รู้จักชุดกฎ "poem" (รองรับ: news, novel, technical, none)`},
+		{"invalid regex", "novel", []CleanRule{{Name: "ยศ", Action: cleanActionReplace, Pattern: `(`}}, "กฎ clean_rules[0] ยศ: pattern ไม่ถูกต้อง"},
+		{"invalid delete pattern", "none", []CleanRule{{Action: cleanActionNormalize}, {Action: cleanActionDeleteLine, Pattern: `[`}}, "กฎ clean_rules[1]"},
+		{"empty map", "none", []CleanRule{{Action: cleanActionMap}}, "ต้องระบุ map"},
+		{"unknown action", "none", []CleanRule{{Action: "upper"}}, `ไม่รู้จัก action "upper"`},
+	}
+	for _, tt := range tests {
+		_, err := newTextCleaner(tt.preset, tt.rules)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: newTextCleaner error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
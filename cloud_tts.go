@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	registerSynthesizer("cloud", func(ctx context.Context, cfg *Config) (Synthesizer, error) {
+		client, err := texttospeech.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ไม่สามารถเชื่อมต่อ Google Cloud TTS: %v", err)
+		}
+		return &cloudTTS{
+			client:  client,
+			profile: cfg.outputProfile(),
+			pauses:  cfg.documentPauses(),
+			limiter: newRateLimiter("cloud", cfg.CloudRPM/60, cfg.CloudBurst),
+		}, nil
+	})
+}
+
+// Cloud TTS ปฏิเสธข้อความที่ยาวเกิน 5000 bytes ต่อคำขอ
+const cloudTTSMaxInputBytes = 5000
+
+// Google Cloud TTS (คุณภาพเสียงสูง ต้องมี credentials)
+type cloudTTS struct {
+	client  *texttospeech.Client
+	profile outputProfile  // รูปแบบ output กำหนด AudioEncoding ที่ขอจาก API
+	pauses  documentPauses // ความยาว <break> ระหว่างย่อหน้าและประโยค
+	limiter *rateLimiter   // quota ต่อนาทีของ API ใช้ร่วมกันทุก worker
+}
+
+func (c *cloudTTS) Name() string {
+	return "cloud"
+}
+
+// แปลงข้อความเป็น SSML แล้วแบ่งให้แต่ละส่วนไม่เกินขีดจำกัดของ API
+func (c *cloudTTS) Chunk(in PreparedText) ([]SynthesisRequest, error) {
+	doc := in.Text
+	if !in.SSML {
+		doc = textToSSML(in.Text, c.pauses)
+	}
+
+	parts, err := splitSSML(doc, cloudTTSMaxInputBytes)
+	if err != nil {
+		return nil, fmt.Errorf("ไม่สามารถแบ่ง SSML ได้: %v", err)
+	}
+
+	var reqs []SynthesisRequest
+	for _, part := range parts {
+		reqs = append(reqs, SynthesisRequest{Text: part, SSML: true})
+	}
+	return reqs, nil
+}
+
+func (c *cloudTTS) Synthesize(ctx context.Context, req SynthesisRequest) (*AudioChunk, error) {
+	// สร้าง request
+	input := &texttospeechpb.SynthesisInput{
+		InputSource: &texttospeechpb.SynthesisInput_Text{Text: req.Text},
+	}
+	if req.SSML {
+		input.InputSource = &texttospeechpb.SynthesisInput_Ssml{Ssml: req.Text}
+	}
+	ttsReq := &texttospeechpb.SynthesizeSpeechRequest{
+		Input: input,
+		Voice: &texttospeechpb.VoiceSelectionParams{
+			LanguageCode: req.Voice.Language,
+			Name:         req.Voice.Name,
+			SsmlGender:   cloudGender(req.Voice.Gender),
+		},
+		AudioConfig: c.audioConfig(),
+	}
+
+	// รอคิวตาม quota แล้วเรียก API
+	err := c.limiter.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.SynthesizeSpeech(ctx, ttsReq)
+	if err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			c.limiter.Throttle()
+		}
+		return nil, fmt.Errorf("ไม่สามารถสร้างเสียงได้: %v", err)
+	}
+	c.limiter.Success()
+
+	return &AudioChunk{Data: resp.AudioContent, Format: cloudEncodingFormat(c.profile.CloudEncoding)}, nil
+}
+
+// การตั้งค่าเสียงที่ขอจาก API ตามโปรไฟล์ output (OGG_OPUS/LINEAR16 ไม่ต้องแปลงจาก MP3)
+func (c *cloudTTS) audioConfig() *texttospeechpb.AudioConfig {
+	return &texttospeechpb.AudioConfig{
+		AudioEncoding:   texttospeechpb.AudioEncoding(texttospeechpb.AudioEncoding_value[c.profile.CloudEncoding]),
+		SampleRateHertz: int32(c.profile.SampleRate),
+		SpeakingRate:    1.0,
+		Pitch:           0.0,
+		VolumeGainDb:    2.0,
+	}
+}
+
+// การตั้งค่าเสียงสำหรับ cache key
+func (c *cloudTTS) AudioConfig() string {
+	ac := c.audioConfig()
+	return fmt.Sprintf("%s/%d/%.2f/%.2f/%.2f", ac.AudioEncoding, ac.SampleRateHertz, ac.SpeakingRate, ac.Pitch, ac.VolumeGainDb)
+}
+
+// แปลงชื่อเพศเป็นค่าของ API (ว่าง = ไม่ระบุ)
+func cloudGender(gender string) texttospeechpb.SsmlVoiceGender {
+	return texttospeechpb.SsmlVoiceGender(texttospeechpb.SsmlVoiceGender_value[strings.ToUpper(gender)])
+}
+
+// ตรวจสอบเสียงที่ร้องขอกับรายการจาก ListVoices ก่อนเริ่มประมวลผล
+func (c *cloudTTS) ValidateVoices(ctx context.Context, voices []VoiceSettings) error {
+	resp, err := c.client.ListVoices(ctx, &texttospeechpb.ListVoicesRequest{})
+	if err != nil {
+		return fmt.Errorf("ไม่สามารถดึงรายการเสียงได้: %v", err)
+	}
+
+	available := map[string]*texttospeechpb.Voice{}
+	languages := map[string]bool{}
+	for _, v := range resp.Voices {
+		available[v.Name] = v
+		for _, lang := range v.LanguageCodes {
+			languages[lang] = true
+		}
+	}
+
+	var problems []string
+	for _, voice := range voices {
+		if voice.Name == "" {
+			if !languages[voice.Language] {
+				problems = append(problems, fmt.Sprintf("ไม่มีเสียงสำหรับภาษา %s", voice.Language))
+			}
+			continue
+		}
+
+		v, ok := available[voice.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("ไม่พบเสียง %s", voice.Name))
+			continue
+		}
+		if !containsString(v.LanguageCodes, voice.Language) {
+			problems = append(problems, fmt.Sprintf("เสียง %s ไม่รองรับภาษา %s (รองรับ: %s)", voice.Name, voice.Language, strings.Join(v.LanguageCodes, ", ")))
+		}
+		if voice.Gender != "" && v.SsmlGender != cloudGender(voice.Gender) {
+			problems = append(problems, fmt.Sprintf("เสียง %s เป็นเพศ %s ไม่ใช่ %s", voice.Name, v.SsmlGender, voice.Gender))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("เสียงไม่ถูกต้อง: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ตัดเสียงความถี่ต่ำและสูงเกินช่วงเสียงพูด และเพิ่ม volume
+// lowpass ต้องต่ำกว่าครึ่งหนึ่งของ sample rate (24kHz -> 10.8kHz)
+func (c *cloudTTS) EnhanceFilters(sampleRate int) []string {
+	return []string{
+		"highpass=f=80",
+		fmt.Sprintf("lowpass=f=%d", min(15000, sampleRate*9/20)),
+		"volume=1.1",
+	}
+}
+
+func (c *cloudTTS) Close() error {
+	return c.client.Close()
+}
+
+// ตรวจสอบว่ามีข้อความใน slice หรือไม่
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// คำสั่ง voices: แสดงรายการเสียงของ Google Cloud TTS
+func runVoicesCommand(args []string) error {
+	fs := flag.NewFlagSet("k-tts voices", flag.ContinueOnError)
+	lang := fs.String("lang", "", "กรองตามรหัสภาษา เช่น th-TH")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, err := texttospeech.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("ไม่สามารถเชื่อมต่อ Google Cloud TTS: %v", err)
+	}
+	defer client.Close()
+
+	resp, err := client.ListVoices(ctx, &texttospeechpb.ListVoicesRequest{LanguageCode: *lang})
+	if err != nil {
+		return fmt.Errorf("ไม่สามารถดึงรายการเสียงได้: %v", err)
+	}
+
+	voices := resp.Voices
+	sort.Slice(voices, func(i, j int) bool {
+		return voices[i].Name < voices[j].Name
+	})
+
+	fmt.Printf("🎤 พบเสียง %d เสียง\n", len(voices))
+	fmt.Printf("%-32s %-8s %-10s %s\n", "NAME", "GENDER", "RATE (Hz)", "LANGUAGES")
+	for _, v := range voices {
+		fmt.Printf("%-32s %-8s %-10d %s\n", v.Name, v.SsmlGender, v.NaturalSampleRateHertz, strings.Join(v.LanguageCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ไฟล์ config ที่อ่านอัตโนมัติหากมีอยู่ใน folder ปัจจุบัน
+const defaultConfigFile = "k-tts.json"
+
+// การตั้งค่าทั้งหมดของโปรแกรม
+// ลำดับความสำคัญ: ค่าเริ่มต้น < ไฟล์ config < environment variables < command-line flags
+type Config struct {
+	Speed      float64 `json:"speed"`      // ความเร็วเสียง (1.0 = ปกติ)
+	Workers    int     `json:"workers"`    // จำนวนไฟล์ที่ประมวลผลพร้อมกัน
+	InputDir   string  `json:"input"`      // folder ไฟล์ข้อความต้นฉบับ หรือไฟล์ .epub
+	OutputDir  string  `json:"output"`     // folder ไฟล์เสียงที่สร้างขึ้น
+	Glob       string  `json:"glob"`       // รูปแบบชื่อไฟล์ใน InputDir (หลายรูปแบบคั่นด้วย ,)
+	Encoding   string  `json:"encoding"`   // การเข้ารหัสของไฟล์ข้อความ: auto, utf-8, utf-16, tis-620, windows-874
+	Engines    string  `json:"engines"`    // ลำดับ engine สำหรับ fallback เช่น "cloud,translate"
+	Voice      string  `json:"voice"`      // ชื่อเสียงของ Google Cloud TTS
+	Language   string  `json:"language"`   // รหัสภาษา เช่น "th-TH"
+	Gender     string  `json:"gender"`     // เพศของเสียง: FEMALE, MALE, NEUTRAL หรือว่างเพื่อไม่ระบุ
+	ChunkSize  int     `json:"chunk_size"` // จำนวนตัวอักษรสูงสุดต่อส่วนสำหรับ Google Translate TTS
+	Dictionary string  `json:"dictionary"` // ไฟล์คำเพิ่มเติมสำหรับตัดคำไทย (หนึ่งคำต่อบรรทัด)
+	Lexicon    string  `json:"lexicon"`    // ไฟล์คำอ่านของชื่อเฉพาะ (คำ = คำอ่าน)
+	Bitrate    string  `json:"bitrate"`    // bitrate ของไฟล์ MP3 ที่สร้าง (โปรไฟล์ mp3)
+	Profile    string  `json:"profile"`    // รูปแบบไฟล์ output เช่น mp3, opus-32k, flac
+
+	ParagraphPause string `json:"paragraph_pause"` // ช่วงเงียบระหว่างย่อหน้า เช่น "700ms"
+	SentencePause  string `json:"sentence_pause"`  // ช่วงเงียบระหว่างประโยค เช่น "250ms" (0 = ไม่เว้น)
+
+	CleanPreset string      `json:"clean_preset"` // ชุดกฎทำความสะอาดข้อความ: novel, news, technical หรือ none
+	CleanRules  []CleanRule `json:"clean_rules"`  // กฎเพิ่มเติมที่ทำต่อจากชุดกฎ ตามลำดับ
+
+	MixedLanguage  bool                   `json:"mixed_language"`   // แยกช่วงภาษาตามอักษร แล้วอ่านแต่ละช่วงด้วยภาษาของตัวเอง
+	MinLanguageRun int                    `json:"min_language_run"` // จำนวนตัวอักษรขั้นต่ำของช่วงภาษา (ช่วงที่สั้นกว่าอ่านด้วยภาษาข้างเคียง)
+	ScriptVoices   map[string]ScriptVoice `json:"script_voices"`    // เสียงของอักษรอื่น: latin, cjk, thai
+
+	AnnounceTitle bool   `json:"announce_title"` // อ่านชื่อบทจากบรรทัดแรกก่อนเนื้อหา
+	TitleTemplate string `json:"title_template"` // รูปแบบข้อความประกาศ: {number} {n} {name}
+	TitleVoice    string `json:"title_voice"`    // เสียงที่ใช้ประกาศชื่อบท (ว่าง = เสียงของบท)
+	TitlePause    string `json:"title_pause"`    // ช่วงเงียบหลังชื่อบท เช่น "1s"
+
+	Book        bool   `json:"book"`         // รวมทุกบทเป็นหนังสือเสียง .m4b พร้อม chapter หลังประมวลผลเสร็จ
+	BookTitle   string `json:"book_title"`   // ชื่อหนังสือ (ว่าง = ชื่อจาก EPUB)
+	Author      string `json:"author"`       // ผู้แต่ง (ว่าง = ผู้แต่งจาก EPUB)
+	Cover       string `json:"cover"`        // ไฟล์รูปปก (ว่าง = รูปปกจาก EPUB)
+	BookBitrate string `json:"book_bitrate"` // bitrate ของ AAC ในหนังสือเสียง
+
+	Subtitles string `json:"subtitles"` // ไฟล์คำบรรยายที่เขียนข้างไฟล์เสียง เช่น "srt,vtt,lrc" (ว่าง = ไม่เขียน)
+
+	CodePhrase  string `json:"code_phrase"`  // ข้อความที่อ่านแทน code block ของ Markdown/HTML (ว่าง = ข้าม)
+	ImagePhrase string `json:"image_phrase"` // ข้อความที่อ่านแทนรูปภาพ ({alt} = คำอธิบายรูป, ว่าง = ข้าม)
+
+	CacheDir     string `json:"cache_dir"`      // folder เก็บเสียงแต่ละส่วน (ว่าง = cache ของผู้ใช้)
+	CacheMaxSize string `json:"cache_max_size"` // ขนาดสูงสุดของ cache เช่น "2GB" (0 = ไม่จำกัด)
+	NoCache      bool   `json:"no_cache"`       // ไม่ใช้ cache
+
+	RetryAttempts  int    `json:"retry_attempts"`   // จำนวนครั้งที่ลองขอเสียงแต่ละส่วน (รวมครั้งแรก)
+	RetryBaseDelay string `json:"retry_base_delay"` // เวลารอก่อนลองใหม่ครั้งแรก (เพิ่มเป็นสองเท่าทุกครั้ง)
+	RetryMaxDelay  string `json:"retry_max_delay"`  // เวลารอสูงสุดต่อครั้ง
+	AllowPartial   bool   `json:"allow_partial"`    // ยอมให้บทขาดบางส่วนได้หลังลองใหม่ครบ (แทนที่จะล้มเหลว)
+
+	TranslateRPS   float64 `json:"translate_rps"`   // คำขอต่อวินาทีของ Translate TTS รวมทุก worker (0 = ไม่จำกัด)
+	TranslateBurst int     `json:"translate_burst"` // จำนวนคำขอที่ส่งติดกันได้ก่อนเริ่มจำกัด
+	CloudRPM       float64 `json:"cloud_rpm"`       // คำขอต่อนาทีของ Cloud TTS ตาม quota ของ project (0 = ไม่จำกัด)
+	CloudBurst     int     `json:"cloud_burst"`     // จำนวนคำขอที่ส่งติดกันได้ก่อนเริ่มจำกัด
+
+	// ตัวเลือกเฉพาะรอบการทำงาน (กำหนดได้ด้วย flags เท่านั้น)
+	Force bool   `json:"-"` // สร้างใหม่ทุกบทแม้ output เป็นปัจจุบัน
+	Only  string `json:"-"` // เลือกเฉพาะบท เช่น "012-020,025"
+
+	// การตั้งค่าเฉพาะบท โดยใช้ชื่อไฟล์ที่ไม่มีนามสกุลเป็น key เช่น "012"
+	Chapters map[string]ChapterConfig `json:"chapters"`
+
+	cleaner  *textCleaner     // กฎทำความสะอาดที่คอมไพล์แล้วใน validate
+	selector *chapterSelector // บทที่เลือกจาก Only แยกแล้วใน validate
+}
+
+// การตั้งค่าที่ใช้ทับค่าหลักสำหรับบางบท
+type ChapterConfig struct {
+	Voice    string `json:"voice"`
+	Language string `json:"language"`
+	Gender   string `json:"gender"`
+	Lexicon  string `json:"lexicon"` // lexicon เพิ่มเติมของบทนี้ (ทับคำเดียวกันใน lexicon หลัก)
+}
+
+// เสียงที่ใช้สังเคราะห์
+type VoiceSettings struct {
+	Name     string
+	Language string
+	Gender   string
+}
+
+// เสียงของบทที่กำหนด (ใช้ค่าหลักหากบทนั้นไม่ได้ตั้งค่าเฉพาะ)
+func (c *Config) voiceFor(chapter string) VoiceSettings {
+	voice := VoiceSettings{Name: c.Voice, Language: c.Language, Gender: c.Gender}
+	override, ok := c.Chapters[chapter]
+	if !ok {
+		return voice
+	}
+	if override.Voice != "" {
+		voice.Name = override.Voice
+	}
+	if override.Language != "" {
+		voice.Language = override.Language
+	}
+	if override.Gender != "" {
+		voice.Gender = strings.ToUpper(override.Gender)
+	}
+	return voice
+}
+
+// ค่าเริ่มต้นของการตั้งค่า
+func defaultConfig() Config {
+	return Config{
+		Speed:     1.6,
+		Workers:   4,
+		InputDir:  "chapters",
+		OutputDir: "output",
+		Glob:      "*.txt,*.ssml,*.epub,*.md,*.html",
+		Encoding:  encodingAuto,
+		Engines:   "cloud,translate",
+		Voice:     "th-TH-Neural2-C",
+		Language:  "th-TH",
+		Gender:    "FEMALE",
+		ChunkSize: 150,
+		Bitrate:   "320k",
+		Profile:   defaultOutputProfile,
+
+		ParagraphPause: "700ms",
+		SentencePause:  "0s",
+		CleanPreset:    "novel",
+		MinLanguageRun: 3,
+		ScriptVoices: map[string]ScriptVoice{
+			scriptLatin: {Voice: "en-US-Neural2-C", Language: "en-US"},
+		},
+		TitleTemplate: "บทที่ {number} {name}",
+		TitlePause:    "1s",
+		BookBitrate:   "64k",
+
+		CacheDir:     defaultCacheDir(),
+		CacheMaxSize: "2GB",
+
+		RetryAttempts:  5,
+		RetryBaseDelay: "1s",
+		RetryMaxDelay:  "30s",
+
+		TranslateRPS:   1.5,
+		TranslateBurst: 2,
+		CloudRPM:       900,
+		CloudBurst:     5,
+	}
+}
+
+// โหลดการตั้งค่าจากไฟล์ config, environment variables และ flags ตามลำดับ
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	// หาไฟล์ config ก่อน เพื่อให้ flags อื่นทับค่าในไฟล์ได้
+	configPath, explicit := configPathFromArgs(args)
+	if configPath == "" {
+		configPath = os.Getenv("KTTS_CONFIG")
+		explicit = configPath != ""
+	}
+	if configPath == "" {
+		configPath = defaultConfigFile
+	}
+	err := cfg.loadFile(configPath, explicit)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.applyEnv(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := flag.NewFlagSet("k-tts", flag.ContinueOnError)
+	fs.String("config", configPath, "ไฟล์ config (JSON)")
+	cfg.bindFlags(fs)
+	err = fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("ไม่รู้จัก argument: %s", strings.Join(fs.Args(), " "))
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// ดึงค่า --config จาก arguments โดยไม่ parse flags อื่น
+func configPathFromArgs(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config="), true
+		}
+	}
+	return "", false
+}
+
+// อ่านไฟล์ config แบบ JSON (ข้ามได้หากไม่ได้ระบุเองและไม่มีไฟล์)
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return fmt.Errorf("ไม่สามารถอ่านไฟล์ config %s: %v", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("ไฟล์ config %s ไม่ถูกต้อง: %v", path, err)
+	}
+	return nil
+}
+
+// ทับค่าด้วย environment variables ที่ขึ้นต้นด้วย KTTS_
+func (c *Config) applyEnv(getenv func(string) string) error {
+	if v := getenv("KTTS_SPEED"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("KTTS_SPEED ไม่ถูกต้อง: %v", err)
+		}
+		c.Speed = speed
+	}
+	if v := getenv("KTTS_WORKERS"); v != "" {
+		workers, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_WORKERS ไม่ถูกต้อง: %v", err)
+		}
+		c.Workers = workers
+	}
+	if v := getenv("KTTS_NO_CACHE"); v != "" {
+		noCache, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_NO_CACHE ไม่ถูกต้อง: %v", err)
+		}
+		c.NoCache = noCache
+	}
+	if v := getenv("KTTS_RETRY_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_RETRY_ATTEMPTS ไม่ถูกต้อง: %v", err)
+		}
+		c.RetryAttempts = attempts
+	}
+	if v := getenv("KTTS_MIXED_LANGUAGE"); v != "" {
+		mixed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_MIXED_LANGUAGE ไม่ถูกต้อง: %v", err)
+		}
+		c.MixedLanguage = mixed
+	}
+	if v := getenv("KTTS_ANNOUNCE_TITLE"); v != "" {
+		announce, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_ANNOUNCE_TITLE ไม่ถูกต้อง: %v", err)
+		}
+		c.AnnounceTitle = announce
+	}
+	if v := getenv("KTTS_BOOK"); v != "" {
+		book, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_BOOK ไม่ถูกต้อง: %v", err)
+		}
+		c.Book = book
+	}
+	if v := getenv("KTTS_ALLOW_PARTIAL"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_ALLOW_PARTIAL ไม่ถูกต้อง: %v", err)
+		}
+		c.AllowPartial = allow
+	}
+	floatFields := map[string]*float64{
+		"KTTS_TRANSLATE_RPS": &c.TranslateRPS,
+		"KTTS_CLOUD_RPM":     &c.CloudRPM,
+	}
+	for name, field := range floatFields {
+		if v := getenv(name); v != "" {
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s ไม่ถูกต้อง: %v", name, err)
+			}
+			*field = value
+		}
+	}
+	intFields := map[string]*int{
+		"KTTS_TRANSLATE_BURST":  &c.TranslateBurst,
+		"KTTS_MIN_LANGUAGE_RUN": &c.MinLanguageRun,
+		"KTTS_CLOUD_BURST":      &c.CloudBurst,
+	}
+	for name, field := range intFields {
+		if v := getenv(name); v != "" {
+			value, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s ไม่ถูกต้อง: %v", name, err)
+			}
+			*field = value
+		}
+	}
+	if v := getenv("KTTS_CHUNK_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("KTTS_CHUNK_SIZE ไม่ถูกต้อง: %v", err)
+		}
+		c.ChunkSize = size
+	}
+
+	stringFields := map[string]*string{
+		"KTTS_INPUT":    &c.InputDir,
+		"KTTS_OUTPUT":   &c.OutputDir,
+		"KTTS_GLOB":     &c.Glob,
+		"KTTS_ENCODING": &c.Encoding,
+		"KTTS_ENGINES":  &c.Engines,
+		"KTTS_VOICE":    &c.Voice,
+		"KTTS_LANGUAGE": &c.Language,
+		"KTTS_GENDER":   &c.Gender,
+		"KTTS_BITRATE":  &c.Bitrate,
+		"KTTS_PROFILE":  &c.Profile,
+
+		"KTTS_DICTIONARY":       &c.Dictionary,
+		"KTTS_LEXICON":          &c.Lexicon,
+		"KTTS_PARAGRAPH_PAUSE":  &c.ParagraphPause,
+		"KTTS_SENTENCE_PAUSE":   &c.SentencePause,
+		"KTTS_CLEAN_PRESET":     &c.CleanPreset,
+		"KTTS_TITLE_TEMPLATE":   &c.TitleTemplate,
+		"KTTS_TITLE_VOICE":      &c.TitleVoice,
+		"KTTS_TITLE_PAUSE":      &c.TitlePause,
+		"KTTS_BOOK_TITLE":       &c.BookTitle,
+		"KTTS_AUTHOR":           &c.Author,
+		"KTTS_COVER":            &c.Cover,
+		"KTTS_BOOK_BITRATE":     &c.BookBitrate,
+		"KTTS_SUBTITLES":        &c.Subtitles,
+		"KTTS_CODE_PHRASE":      &c.CodePhrase,
+		"KTTS_IMAGE_PHRASE":     &c.ImagePhrase,
+		"KTTS_CACHE_DIR":        &c.CacheDir,
+		"KTTS_CACHE_MAX_SIZE":   &c.CacheMaxSize,
+		"KTTS_RETRY_BASE_DELAY": &c.RetryBaseDelay,
+		"KTTS_RETRY_MAX_DELAY":  &c.RetryMaxDelay,
+	}
+	for name, field := range stringFields {
+		if v := getenv(name); v != "" {
+			*field = v
+		}
+	}
+	return nil
+}
+
+// ผูก flags เข้ากับค่าปัจจุบัน (ค่าที่แสดงใน -h คือค่าหลังรวมไฟล์ config และ env แล้ว)
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&c.Speed, "speed", c.Speed, "ความเร็วเสียง (0.5 - 4.0, 1.0 = ปกติ)")
+	fs.IntVar(&c.Workers, "workers", c.Workers, "จำนวนไฟล์ที่ประมวลผลพร้อมกัน")
+	fs.StringVar(&c.InputDir, "input", c.InputDir, "folder ไฟล์ข้อความต้นฉบับ หรือไฟล์ .epub")
+	fs.StringVar(&c.OutputDir, "output", c.OutputDir, "folder ไฟล์เสียงที่สร้างขึ้น")
+	fs.StringVar(&c.Glob, "glob", c.Glob, "รูปแบบชื่อไฟล์ใน folder input (คั่นด้วย ,)")
+	fs.StringVar(&c.Encoding, "encoding", c.Encoding, "การเข้ารหัสของไฟล์ข้อความ (auto, utf-8, utf-16, tis-620, windows-874)")
+	fs.StringVar(&c.Engines, "engines", c.Engines, "ลำดับ engine สำหรับ fallback (คั่นด้วย ,)")
+	fs.StringVar(&c.Voice, "voice", c.Voice, "ชื่อเสียงของ Google Cloud TTS")
+	fs.StringVar(&c.Language, "language", c.Language, "รหัสภาษา")
+	fs.StringVar(&c.Gender, "gender", c.Gender, "เพศของเสียง (FEMALE, MALE, NEUTRAL)")
+	fs.IntVar(&c.ChunkSize, "chunk-size", c.ChunkSize, "จำนวนตัวอักษรสูงสุดต่อส่วนสำหรับ Google Translate TTS")
+	fs.StringVar(&c.Lexicon, "lexicon", c.Lexicon, "ไฟล์คำอ่านของชื่อเฉพาะ (คำ = คำอ่าน)")
+	fs.StringVar(&c.Dictionary, "dictionary", c.Dictionary, "ไฟล์คำเพิ่มเติมสำหรับตัดคำไทย (หนึ่งคำต่อบรรทัด)")
+	fs.StringVar(&c.Bitrate, "bitrate", c.Bitrate, "bitrate ของไฟล์ MP3 เช่น 128k (โปรไฟล์ mp3)")
+	fs.StringVar(&c.Profile, "profile", c.Profile, "รูปแบบไฟล์ output ("+strings.Join(outputProfileNames(), ", ")+")")
+	fs.StringVar(&c.ParagraphPause, "paragraph-pause", c.ParagraphPause, "ช่วงเงียบระหว่างย่อหน้า เช่น 700ms (0 = ไม่เว้น)")
+	fs.StringVar(&c.SentencePause, "sentence-pause", c.SentencePause, "ช่วงเงียบระหว่างประโยค เช่น 250ms (0 = ไม่เว้น)")
+	fs.StringVar(&c.CleanPreset, "clean-preset", c.CleanPreset, "ชุดกฎทำความสะอาดข้อความ (novel, news, technical, none)")
+	fs.BoolVar(&c.MixedLanguage, "mixed-language", c.MixedLanguage, "อ่านข้อความภาษาอื่น (อังกฤษ จีน ฯลฯ) ด้วยภาษาของตัวเอง")
+	fs.IntVar(&c.MinLanguageRun, "min-language-run", c.MinLanguageRun, "จำนวนตัวอักษรขั้นต่ำก่อนสลับภาษา")
+	fs.BoolVar(&c.AnnounceTitle, "announce-title", c.AnnounceTitle, "อ่านชื่อบทจากบรรทัดแรกก่อนเนื้อหา")
+	fs.StringVar(&c.TitleTemplate, "title-template", c.TitleTemplate, "รูปแบบข้อความประกาศชื่อบท ({number}, {n}, {name})")
+	fs.StringVar(&c.TitleVoice, "title-voice", c.TitleVoice, "เสียงของ Google Cloud TTS ที่ใช้ประกาศชื่อบท")
+	fs.StringVar(&c.TitlePause, "title-pause", c.TitlePause, "ช่วงเงียบหลังชื่อบท เช่น 1s")
+	fs.BoolVar(&c.Book, "book", c.Book, "รวมทุกบทเป็นหนังสือเสียง .m4b พร้อม chapter")
+	fs.StringVar(&c.BookTitle, "book-title", c.BookTitle, "ชื่อหนังสือ (ว่าง = ชื่อจาก EPUB)")
+	fs.StringVar(&c.Author, "author", c.Author, "ผู้แต่ง (ว่าง = ผู้แต่งจาก EPUB)")
+	fs.StringVar(&c.Cover, "cover", c.Cover, "ไฟล์รูปปก (ว่าง = รูปปกจาก EPUB)")
+	fs.StringVar(&c.BookBitrate, "book-bitrate", c.BookBitrate, "bitrate ของ AAC ในหนังสือเสียง")
+	fs.StringVar(&c.Subtitles, "subtitles", c.Subtitles, "ไฟล์คำบรรยายที่เขียนข้างไฟล์เสียง เช่น srt,vtt,lrc (ว่าง = ไม่เขียน)")
+	fs.StringVar(&c.CodePhrase, "code-phrase", c.CodePhrase, "ข้อความที่อ่านแทน code block ของ Markdown/HTML (ว่าง = ข้าม)")
+	fs.StringVar(&c.ImagePhrase, "image-phrase", c.ImagePhrase, "ข้อความที่อ่านแทนรูปภาพ เช่น \"ภาพประกอบ {alt}\" (ว่าง = ข้าม)")
+	fs.StringVar(&c.CacheDir, "cache-dir", c.CacheDir, "folder เก็บ cache เสียงแต่ละส่วน")
+	fs.StringVar(&c.CacheMaxSize, "cache-max-size", c.CacheMaxSize, "ขนาดสูงสุดของ cache เช่น 2GB (0 = ไม่จำกัด)")
+	fs.BoolVar(&c.NoCache, "no-cache", c.NoCache, "ไม่ใช้ cache")
+	fs.IntVar(&c.RetryAttempts, "retry-attempts", c.RetryAttempts, "จำนวนครั้งที่ลองขอเสียงแต่ละส่วน (รวมครั้งแรก)")
+	fs.StringVar(&c.RetryBaseDelay, "retry-base-delay", c.RetryBaseDelay, "เวลารอก่อนลองใหม่ครั้งแรก")
+	fs.StringVar(&c.RetryMaxDelay, "retry-max-delay", c.RetryMaxDelay, "เวลารอสูงสุดระหว่างการลองใหม่")
+	fs.BoolVar(&c.AllowPartial, "allow-partial", c.AllowPartial, "ยอมให้บทขาดบางส่วนได้ (แสดงรายการส่วนที่ขาด)")
+	fs.Float64Var(&c.TranslateRPS, "translate-rps", c.TranslateRPS, "คำขอต่อวินาทีของ Translate TTS รวมทุก worker (0 = ไม่จำกัด)")
+	fs.IntVar(&c.TranslateBurst, "translate-burst", c.TranslateBurst, "จำนวนคำขอ Translate TTS ที่ส่งติดกันได้")
+	fs.Float64Var(&c.CloudRPM, "cloud-rpm", c.CloudRPM, "คำขอต่อนาทีของ Cloud TTS รวมทุก worker (0 = ไม่จำกัด)")
+	fs.IntVar(&c.CloudBurst, "cloud-burst", c.CloudBurst, "จำนวนคำขอ Cloud TTS ที่ส่งติดกันได้")
+	fs.BoolVar(&c.Force, "force", c.Force, "สร้างใหม่ทุกบทแม้ output เป็นปัจจุบัน")
+	fs.StringVar(&c.Only, "only", c.Only, "เลือกเฉพาะบทตามหมายเลข เช่น 012-020,025")
+}
+
+// ตรวจสอบความถูกต้องของการตั้งค่า (ส่วนอื่นใช้ค่าหลังผ่าน validate ได้โดยไม่ต้องตรวจซ้ำ)
+func (c *Config) validate() error {
+	if c.Speed < 0.5 || c.Speed > 4.0 {
+		return fmt.Errorf("speed ต้องอยู่ระหว่าง 0.5 - 4.0 (ได้ %.2f)", c.Speed)
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("workers ต้องมีอย่างน้อย 1 (ได้ %d)", c.Workers)
+	}
+	if c.ChunkSize < 10 || c.ChunkSize > 200 {
+		return fmt.Errorf("chunk_size ต้องอยู่ระหว่าง 10 - 200 (ได้ %d)", c.ChunkSize)
+	}
+	if c.InputDir == "" || c.OutputDir == "" || c.Glob == "" {
+		return fmt.Errorf("ต้องระบุ input, output และ glob")
+	}
+	encoding, ok := canonicalEncoding(c.Encoding)
+	if !ok {
+		return fmt.Errorf("encoding ต้องเป็น %s (ได้ %q)", strings.Join(supportedEncodings, ", "), c.Encoding)
+	}
+	c.Encoding = encoding
+	if c.Cover != "" {
+		if _, err := os.Stat(c.Cover); err != nil {
+			return fmt.Errorf("ไม่พบไฟล์รูปปก %s", c.Cover)
+		}
+	}
+	if c.Language == "" {
+		return fmt.Errorf("ต้องระบุ language")
+	}
+	c.Gender = strings.ToUpper(c.Gender)
+	if !isValidGender(c.Gender) {
+		return fmt.Errorf("gender ต้องเป็น FEMALE, MALE หรือ NEUTRAL (ได้ %q)", c.Gender)
+	}
+	for chapter, override := range c.Chapters {
+		if !isValidGender(strings.ToUpper(override.Gender)) {
+			return fmt.Errorf("gender ของบท %s ต้องเป็น FEMALE, MALE หรือ NEUTRAL (ได้ %q)", chapter, override.Gender)
+		}
+	}
+	if kbps, err := strconv.Atoi(strings.TrimSuffix(c.Bitrate, "k")); err != nil || kbps <= 0 || !strings.HasSuffix(c.Bitrate, "k") {
+		return fmt.Errorf("bitrate ต้องอยู่ในรูปแบบเช่น 128k (ได้ %q)", c.Bitrate)
+	}
+	if _, ok := outputProfiles[c.Profile]; !ok {
+		return fmt.Errorf("profile ต้องเป็น %s (ได้ %q)", strings.Join(outputProfileNames(), ", "), c.Profile)
+	}
+	if pause, err := time.ParseDuration(c.ParagraphPause); err != nil || pause < 0 {
+		return fmt.Errorf("paragraph_pause ต้องเป็นช่วงเวลาเช่น 700ms (ได้ %q)", c.ParagraphPause)
+	}
+	if c.MinLanguageRun < 1 {
+		return fmt.Errorf("min_language_run ต้องมีอย่างน้อย 1 (ได้ %d)", c.MinLanguageRun)
+	}
+	for script, voice := range c.ScriptVoices {
+		if script != scriptThai && script != scriptLatin && script != scriptCJK {
+			return fmt.Errorf("script_voices รองรับเฉพาะ thai, latin และ cjk (ได้ %q)", script)
+		}
+		if voice.Language == "" {
+			return fmt.Errorf("ต้องระบุ language ของ script_voices.%s", script)
+		}
+		if !isValidGender(strings.ToUpper(voice.Gender)) {
+			return fmt.Errorf("gender ของ script_voices.%s ต้องเป็น FEMALE, MALE หรือ NEUTRAL (ได้ %q)", script, voice.Gender)
+		}
+	}
+	if pause, err := time.ParseDuration(c.SentencePause); err != nil || pause < 0 {
+		return fmt.Errorf("sentence_pause ต้องเป็นช่วงเวลาเช่น 250ms (ได้ %q)", c.SentencePause)
+	}
+	if pause, err := time.ParseDuration(c.TitlePause); err != nil || pause < 0 {
+		return fmt.Errorf("title_pause ต้องเป็นช่วงเวลาเช่น 1s (ได้ %q)", c.TitlePause)
+	}
+	if c.AnnounceTitle && strings.TrimSpace(c.TitleTemplate) == "" {
+		return fmt.Errorf("ต้องระบุ title_template เมื่อเปิด announce_title")
+	}
+	for _, format := range c.subtitleFormats() {
+		if !containsString(supportedSubtitleFormats, format) {
+			return fmt.Errorf("subtitles ต้องเป็น %s (ได้ %q)", strings.Join(supportedSubtitleFormats, ", "), format)
+		}
+	}
+	cleaner, err := newTextCleaner(c.CleanPreset, c.CleanRules)
+	if err != nil {
+		return err
+	}
+	c.cleaner = cleaner
+	if _, err := parseByteSize(c.CacheMaxSize); err != nil {
+		return fmt.Errorf("cache_max_size ไม่ถูกต้อง: %v", err)
+	}
+	if c.RetryAttempts < 1 {
+		return fmt.Errorf("retry_attempts ต้องมีอย่างน้อย 1 (ได้ %d)", c.RetryAttempts)
+	}
+	for name, value := range map[string]string{"retry_base_delay": c.RetryBaseDelay, "retry_max_delay": c.RetryMaxDelay} {
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("%s ต้องเป็นช่วงเวลาเช่น 1s (ได้ %q)", name, value)
+		}
+	}
+	if c.TranslateRPS < 0 || c.CloudRPM < 0 {
+		return fmt.Errorf("translate_rps และ cloud_rpm ต้องไม่ติดลบ")
+	}
+	if c.TranslateBurst < 1 || c.CloudBurst < 1 {
+		return fmt.Errorf("translate_burst และ cloud_burst ต้องมีอย่างน้อย 1")
+	}
+	c.selector, err = parseChapterSelector(c.Only)
+	if err != nil {
+		return err
+	}
+	if c.CacheDir == "" {
+		c.CacheDir = defaultCacheDir()
+	}
+	_, err = parseEngineChain(c.Engines)
+	return err
+}
+
+// ตรวจสอบค่าเพศของเสียง (ว่างได้)
+func isValidGender(gender string) bool {
+	switch gender {
+	case "", "FEMALE", "MALE", "NEUTRAL":
+		return true
+	}
+	return false
+}
+
+// นโยบายการลองใหม่จากการตั้งค่า
+func (c *Config) retryPolicy() RetryPolicy {
+	base, _ := time.ParseDuration(c.RetryBaseDelay)
+	max, _ := time.ParseDuration(c.RetryMaxDelay)
+	return RetryPolicy{
+		MaxAttempts: c.RetryAttempts,
+		BaseDelay:   base,
+		MaxDelay:    max,
+		Jitter:      0.5,
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// เอกสารข้อความที่เก็บโครงสร้างย่อหน้าและประโยคไว้ตลอดการแบ่งส่วน
+type textDocument struct {
+	Paragraphs []textParagraph
+}
+
+// ย่อหน้าหนึ่งประกอบด้วยประโยคตามลำดับ
+type textParagraph struct {
+	Sentences []string
+}
+
+// แยกข้อความที่ทำความสะอาดแล้ว (ย่อหน้าละบรรทัด) เป็นย่อหน้าและประโยค
+func parseTextDocument(text string) textDocument {
+	var doc textDocument
+	for _, line := range strings.Split(text, "\n") {
+		doc.addParagraph(line)
+	}
+	return doc
+}
+
+// เพิ่มย่อหน้าจากข้อความ (ข้ามข้อความว่าง)
+func (d *textDocument) addParagraph(text string) {
+	var paragraph textParagraph
+	paragraph.addSentences(text)
+	if len(paragraph.Sentences) > 0 {
+		d.Paragraphs = append(d.Paragraphs, paragraph)
+	}
+}
+
+// เพิ่มประโยคจากข้อความ โดยแบ่งตามเครื่องหมายจบประโยค
+func (p *textParagraph) addSentences(text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	p.Sentences = append(p.Sentences, splitIntoSentences(text)...)
+}
+
+// ข้อความของย่อหน้า (ประโยคคั่นด้วยช่องว่าง)
+func (p textParagraph) String() string {
+	return strings.Join(p.Sentences, " ")
+}
+
+// ข้อความของเอกสาร ย่อหน้าละบรรทัด
+func (d textDocument) String() string {
+	lines := make([]string, len(d.Paragraphs))
+	for i, p := range d.Paragraphs {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ช่วงเงียบระหว่างส่วนของเอกสาร (รูปแบบเดียวกับ time ของ SSML <break> เช่น "700ms")
+type documentPauses struct {
+	Sentence  string
+	Paragraph string
+}
+
+// ช่วงเงียบจากการตั้งค่า
+func (c *Config) documentPauses() documentPauses {
+	return documentPauses{Sentence: c.SentencePause, Paragraph: c.ParagraphPause}
+}
+
+// แปลงความยาวช่วงเงียบ (ว่างหรือไม่ถูกต้อง = ไม่เว้น)
+func pauseDuration(pause string) time.Duration {
+	d, err := time.ParseDuration(pause)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// tag <break> ของช่วงเงียบ (ว่างหากไม่เว้น)
+func ssmlBreak(pause string) string {
+	if pauseDuration(pause) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(`<break time="%s"/>`, pause)
+}
+
+// แปลงเอกสารเป็น SSML โดยใช้ render แปลงข้อความแต่ละส่วน
+// ใส่ <s> และ <break> ระหว่างประโยคเฉพาะเมื่อกำหนดช่วงเงียบระหว่างประโยค
+func (d textDocument) ssml(pauses documentPauses, render func(string) string) string {
+	sentenceBreak := ssmlBreak(pauses.Sentence)
+
+	var sb strings.Builder
+	sb.WriteString("<speak>")
+	for i, paragraph := range d.Paragraphs {
+		if i > 0 {
+			sb.WriteString(ssmlBreak(pauses.Paragraph))
+		}
+		sb.WriteString("<p>")
+		if sentenceBreak == "" {
+			sb.WriteString(render(paragraph.String()))
+		} else {
+			for j, sentence := range paragraph.Sentences {
+				if j > 0 {
+					sb.WriteString(sentenceBreak)
+				}
+				sb.WriteString("<s>" + render(sentence) + "</s>")
+			}
+		}
+		sb.WriteString("</p>")
+	}
+	sb.WriteString("</speak>")
+	return sb.String()
+}
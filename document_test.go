@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTextDocument(t *testing.T) {
+	text := "เขาเดินออกไป.  แล้วหยุด! ไปไหน?\n\n   \nราคา 3.14 บาท   ถูกมาก\nจบ."
+	want := textDocument{Paragraphs: []textParagraph{
+		{Sentences: []string{"เขาเดินออกไป.", "แล้วหยุด!", "ไปไหน?"}},
+		// ทศนิยมไม่ใช่จุดจบประโยค และช่องว่างซ้ำถูกรวม
+		{Sentences: []string{"ราคา 3.14 บาท ถูกมาก"}},
+		{Sentences: []string{"จบ."}},
+	}}
+	got := parseTextDocument(text)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTextDocument =\n%+v\nwant\n%+v", got, want)
+	}
+	if s, want := got.String(), "เขาเดินออกไป. แล้วหยุด! ไปไหน?\nราคา 3.14 บาท ถูกมาก\nจบ."; s != want {
+		t.Errorf("String = %q, want %q", s, want)
+	}
+	if doc := parseTextDocument("\n  \n"); len(doc.Paragraphs) != 0 {
+		t.Errorf("parseTextDocument of blank text = %+v, want no paragraphs", doc)
+	}
+}
+
+func TestTextDocumentSSML(t *testing.T) {
+	doc := parseTextDocument("หนึ่ง. สอง & สาม.\nสี่.")
+	tests := []struct {
+		name   string
+		pauses documentPauses
+		want   string
+	}{
+		// ค่าเริ่มต้น: เว้นเฉพาะระหว่างย่อหน้า ประโยคอ่านต่อกัน
+		{"paragraph only", documentPauses{Sentence: "0s", Paragraph: "700ms"},
+			`<speak><p>หนึ่ง. สอง &amp; สาม.</p><break time="700ms"/><p>สี่.</p></speak>`},
+		{"sentence and paragraph", documentPauses{Sentence: "300ms", Paragraph: "1s"},
+			`<speak><p><s>หนึ่ง.</s><break time="300ms"/><s>สอง &amp; สาม.</s></p><break time="1s"/><p><s>สี่.</s></p></speak>`},
+		// ค่าว่างหรือไม่ถูกต้องไม่เว้น
+		{"none", documentPauses{Sentence: "", Paragraph: "soon"},
+			`<speak><p>หนึ่ง. สอง &amp; สาม.</p><p>สี่.</p></speak>`},
+	}
+	for _, tt := range tests {
+		if got := doc.ssml(tt.pauses, escapeSSML); got != tt.want {
+			t.Errorf("%s: ssml =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPauseDuration(t *testing.T) {
+	tests := []struct {
+		pause string
+		want  time.Duration
+		tag   string
+	}{
+		{"700ms", 700 * time.Millisecond, `<break time="700ms"/>`},
+		{"1.5s", 1500 * time.Millisecond, `<break time="1.5s"/>`},
+		{"0s", 0, ""},
+		{"", 0, ""},
+		{"-1s", 0, ""},
+		{"นาน", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := pauseDuration(tt.pause); got != tt.want {
+			t.Errorf("pauseDuration(%q) = %v, want %v", tt.pause, got, tt.want)
+		}
+		if got := ssmlBreak(tt.pause); got != tt.tag {
+			t.Errorf("ssmlBreak(%q) = %q, want %q", tt.pause, got, tt.tag)
+		}
+	}
+}
+
+func TestTranslateChunkPauses(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ParagraphPause = "700ms"
+	text := "หนึ่ง. สอง.\nสาม."
+
+	tests := []struct {
+		sentencePause string
+		want          []SynthesisRequest
+	}{
+		{"0s", []SynthesisRequest{
+			{Text: "หนึ่ง. สอง."},
+			{Pause: 700 * time.Millisecond},
+			{Text: "สาม."},
+		}},
+		{"300ms", []SynthesisRequest{
+			{Text: "หนึ่ง."},
+			{Pause: 300 * time.Millisecond},
+			{Text: "สอง."},
+			{Pause: 700 * time.Millisecond},
+			{Text: "สาม."},
+		}},
+	}
+	for _, tt := range tests {
+		cfg.SentencePause = tt.sentencePause
+		got, err := newTranslateTTS(&cfg).Chunk(PreparedText{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sentence pause %s: Chunk =\n%+v\nwant\n%+v", tt.sentencePause, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// การเข้ารหัสที่รองรับ
+const (
+	encodingAuto       = "auto"
+	encodingUTF8       = "utf-8"
+	encodingUTF16      = "utf-16"
+	encodingTIS620     = "tis-620"
+	encodingWindows874 = "windows-874"
+)
+
+var supportedEncodings = []string{encodingAuto, encodingUTF8, encodingUTF16, encodingTIS620, encodingWindows874}
+
+// ชื่อการเข้ารหัสแบบมาตรฐาน (รับชื่ออื่นที่พบบ่อยด้วย)
+func canonicalEncoding(name string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return encodingAuto, true
+	case "utf-8", "utf8":
+		return encodingUTF8, true
+	case "utf-16", "utf16":
+		return encodingUTF16, true
+	case "tis-620", "tis620", "iso-8859-11":
+		return encodingTIS620, true
+	case "windows-874", "cp874", "x-windows-874":
+		return encodingWindows874, true
+	}
+	return "", false
+}
+
+// ตัวอักษรเพิ่มเติมของ Windows-874 ในช่วง 0x80-0xA0
+var windows874Extras = map[byte]rune{
+	0x80: '€',
+	0x85: '…',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—',
+	0xA0: '\u00A0',
+}
+
+// แปลง byte หนึ่งตัวของ TIS-620 เป็นตัวอักษร (false = ไม่มีใน TIS-620)
+func tis620Rune(b byte) (rune, bool) {
+	switch {
+	case b < 0x80:
+		return rune(b), true
+	case b >= 0xA1 && b <= 0xDA, b >= 0xDF && b <= 0xFB:
+		return 0x0E01 + rune(b-0xA1), true
+	}
+	return 0, false
+}
+
+// แปลงข้อความ TIS-620 หรือ Windows-874 เป็น UTF-8
+func decodeThaiCodepage(data []byte, windows bool) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(data) * 3)
+	for i, b := range data {
+		if r, ok := tis620Rune(b); ok {
+			sb.WriteRune(r)
+			continue
+		}
+		if r, ok := windows874Extras[b]; ok && windows {
+			sb.WriteRune(r)
+			continue
+		}
+		return "", fmt.Errorf("byte 0x%02X ที่ตำแหน่ง %d ไม่มีในตารางอักษร", b, i)
+	}
+	return sb.String(), nil
+}
+
+// แปลง UTF-16 ที่มี BOM เป็น UTF-8
+func decodeUTF16(data []byte) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("ความยาวไม่ใช่จำนวนคู่")
+	}
+	bigEndian := bytes.HasPrefix(data, []byte{0xFE, 0xFF})
+	if bigEndian || bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+		data = data[2:]
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	text := string(utf16.Decode(units))
+	if strings.ContainsRune(text, utf8.RuneError) {
+		return "", fmt.Errorf("มี surrogate ที่ไม่ครบคู่")
+	}
+	return text, nil
+}
+
+// สัดส่วนขั้นต่ำของ byte อักษรไทยใน byte ที่เกิน 0x7F ก่อนจะถือว่าเป็นภาษาไทย
+// (ส่วนที่เหลือเป็นเครื่องหมายของ Windows-874 เช่น อัญประกาศ)
+const thaiCodepageMinRatio = 0.5
+
+// ประเภทของ byte ใน TIS-620 ที่ใช้ตรวจลำดับอักษร
+func tis620Consonant(b byte) bool { return b >= 0xA1 && b <= 0xCE }
+
+// สระบน สระล่าง วรรณยุกต์ และเครื่องหมายที่ต้องวางบนหรือใต้พยัญชนะ
+func tis620Combining(b byte) bool {
+	return b == 0xD1 || b >= 0xD4 && b <= 0xDA || b >= 0xE7 && b <= 0xEE
+}
+
+// สระหน้า เ แ โ ใ ไ
+func tis620Leading(b byte) bool { return b >= 0xE0 && b <= 0xE4 }
+
+// ตำแหน่งแรกที่ลำดับอักษรผิดหลักภาษาไทย (-1 = ถูกต้องทั้งหมด):
+// สระบน/ล่างและวรรณยุกต์ต้องตามหลังพยัญชนะ (หรือเครื่องหมายบน/ล่างอีกตัว)
+// และสระหน้าต้องตามด้วยพยัญชนะ
+func implausibleThai(data []byte) int {
+	for i, b := range data {
+		switch {
+		case tis620Combining(b):
+			if i == 0 || !tis620Consonant(data[i-1]) && !tis620Combining(data[i-1]) {
+				return i
+			}
+		case tis620Leading(b):
+			if i+1 >= len(data) || !tis620Consonant(data[i+1]) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// เดาการเข้ารหัสของข้อความ: BOM, UTF-8 ที่ถูกต้อง แล้วจึง TIS-620/Windows-874
+func detectEncoding(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return encodingUTF8, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return encodingUTF16, nil
+	case utf8.Valid(data):
+		return encodingUTF8, nil
+	}
+
+	// byte ที่เกิน 0x7F ต้องอยู่ในตารางอักษรทั้งหมด และส่วนใหญ่เป็นอักษรไทย
+	high, thai, windows := 0, 0, false
+	for _, b := range data {
+		if b < 0x80 {
+			continue
+		}
+		high++
+		if _, ok := tis620Rune(b); ok {
+			thai++
+		} else if _, ok := windows874Extras[b]; ok {
+			windows = true
+		} else {
+			return "", fmt.Errorf("ไม่ใช่ UTF-8 และมี byte 0x%02X ที่ไม่มีใน TIS-620/Windows-874", b)
+		}
+	}
+	if float64(thai) < float64(high)*thaiCodepageMinRatio {
+		return "", fmt.Errorf("ไม่ใช่ UTF-8 และไม่เหมือนข้อความภาษาไทย TIS-620/Windows-874")
+	}
+	// Latin-1 และ codepage อื่นมักตกอยู่ในตารางอักษรไทยได้ (เช่น é = 0xE9 = ไม้โท)
+	// แต่ลำดับอักษรจะผิดหลักการเขียนภาษาไทย
+	if i := implausibleThai(data); i >= 0 {
+		return "", fmt.Errorf("ไม่ใช่ UTF-8 และลำดับอักษรที่ตำแหน่ง %d ไม่ใช่ภาษาไทย TIS-620/Windows-874", i)
+	}
+	if windows {
+		return encodingWindows874, nil
+	}
+	return encodingTIS620, nil
+}
+
+// แปลงข้อมูลเป็นข้อความ UTF-8 ตามการเข้ารหัสที่กำหนด (auto = ตรวจหาเอง)
+// คืนการเข้ารหัสที่ใช้จริงด้วย
+func decodeText(data []byte, encoding string) (string, string, error) {
+	name, ok := canonicalEncoding(encoding)
+	if !ok {
+		return "", "", fmt.Errorf("ไม่รองรับการเข้ารหัส %q", encoding)
+	}
+	encoding = name
+	if encoding == encodingAuto {
+		detected, err := detectEncoding(data)
+		if err != nil {
+			return "", "", err
+		}
+		encoding = detected
+	}
+
+	var text string
+	var err error
+	switch encoding {
+	case encodingUTF8:
+		data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+		if !utf8.Valid(data) {
+			return "", encoding, fmt.Errorf("ไม่ใช่ UTF-8 ที่ถูกต้อง")
+		}
+		text = string(data)
+	case encodingUTF16:
+		text, err = decodeUTF16(data)
+	case encodingTIS620:
+		text, err = decodeThaiCodepage(data, false)
+	case encodingWindows874:
+		text, err = decodeThaiCodepage(data, true)
+	}
+	if err != nil {
+		return "", encoding, fmt.Errorf("ไม่ใช่ %s ที่ถูกต้อง: %v", encoding, err)
+	}
+	return text, encoding, nil
+}
+
+// อ่านไฟล์ข้อความและแปลงเป็น UTF-8
+func readTextFile(path, encoding string) (string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return decodeText(data, encoding)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// เข้ารหัสข้อความเป็น TIS-620 (อักษรไทยและ ASCII เท่านั้น)
+func tis620(s string) []byte {
+	var out []byte
+	for _, r := range s {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		} else {
+			out = append(out, byte(r-0x0E01+0xA1))
+		}
+	}
+	return out
+}
+
+// เข้ารหัสข้อความเป็น UTF-16 พร้อม BOM
+func utf16Bytes(s string, order binary.AppendByteOrder) []byte {
+	out := order.AppendUint16(nil, 0xFEFF)
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = order.AppendUint16(out, u)
+	}
+	return out
+}
+
+const encodingTestText = "สวัสดีครับ น้ำใจ เก่ง 100 บาท"
+
+func TestDecodeTextAuto(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{"utf-8", []byte(encodingTestText), encodingTestText, encodingUTF8},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, encodingTestText...), encodingTestText, encodingUTF8},
+		{"utf-16le", utf16Bytes(encodingTestText+" 😊", binary.LittleEndian), encodingTestText + " 😊", encodingUTF16},
+		{"utf-16be", utf16Bytes(encodingTestText, binary.BigEndian), encodingTestText, encodingUTF16},
+		{"tis-620", tis620(encodingTestText), encodingTestText, encodingTIS620},
+		// เครื่องหมายในช่วง 0x80-0x9F มีเฉพาะใน Windows-874
+		{"windows-874", bytes.Join([][]byte{{0x93}, tis620("สวัสดี"), {0x94, 0x85, ' ', 0x96, ' ', 0x80}, tis620("5")}, nil), "“สวัสดี”… – €5", encodingWindows874},
+	}
+	for _, tt := range tests {
+		got, used, err := decodeText(tt.data, encodingAuto)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want || used != tt.encoding {
+			t.Errorf("%s: decodeText = %q, %s, want %q, %s", tt.name, got, used, tt.want, tt.encoding)
+		}
+	}
+}
+
+func TestDecodeTextRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		// Latin-1: é (0xE9) คือไม้โทที่ไม่มีพยัญชนะนำหน้า
+		{"latin-1", []byte("caf\xe9 d\xe9j\xe0 vu")},
+		// à (0xE0) คือสระเอที่ไม่มีพยัญชนะตามหลัง
+		{"leading vowel", []byte("voil\xe0")},
+		{"combining at start", tis620("่กา")},
+		{"undefined byte", append(tis620("สวัสดี"), 0xFC)},
+		{"mostly punctuation", []byte{0x93, 0x94, 0x85, 0xA1}},
+		{"odd utf-16", append(utf16Bytes("ก", binary.LittleEndian), 0x00)},
+		{"lone surrogate", []byte{0xFF, 0xFE, 0x3D, 0xD8}},
+	}
+	for _, tt := range tests {
+		if got, used, err := decodeText(tt.data, encodingAuto); err == nil {
+			t.Errorf("%s: decodeText = %q as %s, want error", tt.name, got, used)
+		}
+	}
+}
+
+func TestDecodeTextExplicit(t *testing.T) {
+	// --encoding ไม่ตรวจลำดับอักษร
+	if got, _, err := decodeText([]byte("caf\xe9"), "iso-8859-11"); err != nil || got != "caf้" {
+		t.Errorf("decodeText(tis-620) = %q, %v", got, err)
+	}
+	// Windows-874 punctuation ไม่มีใน TIS-620
+	if _, _, err := decodeText([]byte{0x93}, encodingTIS620); err == nil {
+		t.Error("decodeText(0x93, tis-620) succeeded, want error")
+	}
+	if _, _, err := decodeText(tis620("ไทย"), encodingUTF8); err == nil {
+		t.Error("decodeText(tis-620 bytes, utf-8) succeeded, want error")
+	}
+	if _, _, err := decodeText(nil, "ebcdic"); err == nil {
+		t.Error("decodeText with an unknown encoding succeeded, want error")
+	}
+}
+
+func TestImplausibleThai(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"น้ำใจ", -1},
+		// สระบนตามด้วยวรรณยุกต์
+		{"ที่นี่", -1},
+		{"กก ่", 3},
+		{"เ1", 0},
+		{"ไ", 0},
+	}
+	for _, tt := range tests {
+		if got := implausibleThai(tis620(tt.text)); got != tt.want {
+			t.Errorf("implausibleThai(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ข้อมูลหนังสือจาก EPUB สำหรับ metadata ของไฟล์เสียง
+type bookInfo struct {
+	Title     string
+	Author    string
+	Cover     []byte // รูปปก (nil = ไม่มี)
+	CoverType string // media type ของรูปปก เช่น "image/jpeg"
+	CoverPath string // ไฟล์รูปปกที่บันทึกใน output folder (ว่าง = ยังไม่บันทึก)
+}
+
+// บทหนึ่งของ EPUB ตามลำดับ spine
+type epubChapter struct {
+	Href  string // path ของไฟล์ XHTML ภายใน EPUB
+	Title string // ชื่อบทจากสารบัญ nav หรือ NCX หรือหัวข้อต้นบท (ว่าง = ไม่มี)
+	Text  string // ข้อความย่อหน้าละบรรทัด
+}
+
+// META-INF/container.xml
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// ไฟล์ OPF: metadata, manifest และ spine
+type epubPackage struct {
+	Metadata struct {
+		Titles   []string `xml:"title"`
+		Creators []string `xml:"creator"`
+		Metas    []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Items []epubItem `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type epubItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// สารบัญแบบ NCX ของ EPUB 2
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+type ncxDocument struct {
+	Points []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+// ตรวจสอบว่า item มี property ที่กำหนด เช่น "nav", "cover-image"
+func (item epubItem) hasProperty(name string) bool {
+	for _, p := range strings.Fields(item.Properties) {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// path ภายใน EPUB ของ href ที่อ้างจากไฟล์ใน dir (ตัด #fragment ออก)
+func epubPath(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(dir, href)
+}
+
+// อ่าน EPUB: บทตามลำดับ spine (ข้าม item ที่ linear="no" และบทที่ไม่มีข้อความ)
+// พร้อมชื่อบทจากสารบัญ และชื่อหนังสือ ผู้แต่ง และรูปปก
+func readEPUB(file string, phrases markupPhrases) (*bookInfo, []epubChapter, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zr.Close()
+
+	entries := map[string]*zip.File{}
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := entries[name]
+		if !ok {
+			return nil, fmt.Errorf("ไม่พบ %s ใน EPUB", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	unmarshal := func(name string, v any) error {
+		data, err := read(name)
+		if err != nil {
+			return err
+		}
+		err = xml.Unmarshal(data, v)
+		if err != nil {
+			return fmt.Errorf("%s ไม่ถูกต้อง: %v", name, err)
+		}
+		return nil
+	}
+
+	var container epubContainer
+	err = unmarshal("META-INF/container.xml", &container)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(container.Rootfiles) == 0 || container.Rootfiles[0].FullPath == "" {
+		return nil, nil, fmt.Errorf("container.xml ไม่ระบุไฟล์ OPF")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	err = unmarshal(opfPath, &pkg)
+	if err != nil {
+		return nil, nil, err
+	}
+	opfDir := path.Dir(opfPath)
+
+	book := &bookInfo{Author: strings.Join(normalizedStrings(pkg.Metadata.Creators), ", ")}
+	if titles := normalizedStrings(pkg.Metadata.Titles); len(titles) > 0 {
+		book.Title = titles[0]
+	}
+
+	items := map[string]epubItem{}
+	for _, item := range pkg.Items {
+		items[item.ID] = item
+	}
+
+	// ชื่อบทจากสารบัญ nav (EPUB 3) หรือ NCX (EPUB 2)
+	titles := map[string]string{}
+	for _, item := range pkg.Items {
+		if !item.hasProperty("nav") {
+			continue
+		}
+		navPath := epubPath(opfDir, item.Href)
+		data, err := read(navPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = parseNavTitles(data, path.Dir(navPath), titles)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s ไม่ถูกต้อง: %v", navPath, err)
+		}
+	}
+	if ncx, ok := items[pkg.Spine.Toc]; ok && len(titles) == 0 {
+		ncxPath := epubPath(opfDir, ncx.Href)
+		var doc ncxDocument
+		err = unmarshal(ncxPath, &doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		addNCXTitles(doc.Points, path.Dir(ncxPath), titles)
+	}
+
+	// รูปปก: item ที่มี property cover-image (EPUB 3) หรือ <meta name="cover"> (EPUB 2)
+	var cover epubItem
+	for _, item := range pkg.Items {
+		if item.hasProperty("cover-image") {
+			cover = item
+			break
+		}
+	}
+	for _, meta := range pkg.Metadata.Metas {
+		if cover.Href == "" && meta.Name == "cover" {
+			cover = items[meta.Content]
+		}
+	}
+	if cover.Href != "" && strings.HasPrefix(cover.MediaType, "image/") {
+		book.Cover, err = read(epubPath(opfDir, cover.Href))
+		if err != nil {
+			return nil, nil, err
+		}
+		book.CoverType = cover.MediaType
+	}
+
+	var chapters []epubChapter
+	for _, ref := range pkg.Spine.Itemrefs {
+		if ref.Linear == "no" {
+			continue
+		}
+		item, ok := items[ref.IDRef]
+		if !ok {
+			return nil, nil, fmt.Errorf("spine อ้างถึง item %q ที่ไม่มีใน manifest", ref.IDRef)
+		}
+		name := epubPath(opfDir, item.Href)
+		data, err := read(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err := parseHTML(data, phrases)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s ไม่ถูกต้อง: %v", name, err)
+		}
+		if len(doc.Paragraphs) == 0 {
+			continue
+		}
+		title, ok := titles[name]
+		if !ok {
+			title = doc.Title
+		}
+		chapters = append(chapters, epubChapter{Href: name, Title: title, Text: doc.String()})
+	}
+	if len(chapters) == 0 {
+		return nil, nil, fmt.Errorf("ไม่มีบทที่มีข้อความใน EPUB")
+	}
+	return book, chapters, nil
+}
+
+// ข้อความที่ตัดช่องว่างซ้ำออกแล้ว (ข้ามข้อความว่าง)
+func normalizedStrings(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.Join(strings.Fields(v), " "); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// เก็บชื่อบทจากลิงก์ใน <nav epub:type="toc"> (ชื่อแรกของแต่ละไฟล์)
+func parseNavTitles(data []byte, dir string, titles map[string]string) error {
+	dec := newMarkupDecoder(bytes.NewReader(data))
+	depth := 0 // ความลึกภายใน nav สารบัญ (0 = อยู่นอก)
+	inLink := false
+	var href string
+	var label strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 {
+				depth++
+			} else if t.Name.Local == "nav" && strings.Contains(markupAttr(t, "type"), "toc") {
+				depth = 1
+			}
+			if depth > 0 && t.Name.Local == "a" {
+				inLink = true
+				href = markupAttr(t, "href")
+				label.Reset()
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				continue
+			}
+			if inLink && t.Name.Local == "a" {
+				addEPUBTitle(titles, epubPath(dir, href), label.String())
+				inLink = false
+			}
+			depth--
+		case xml.CharData:
+			if inLink {
+				label.Write(t)
+			}
+		}
+	}
+}
+
+// เก็บชื่อบทจาก navPoint ของ NCX ตามลำดับ
+func addNCXTitles(points []ncxNavPoint, dir string, titles map[string]string) {
+	for _, p := range points {
+		addEPUBTitle(titles, epubPath(dir, p.Content.Src), p.Label)
+		addNCXTitles(p.Children, dir, titles)
+	}
+}
+
+func addEPUBTitle(titles map[string]string, name, label string) {
+	label = strings.Join(strings.Fields(label), " ")
+	if _, ok := titles[name]; !ok && label != "" {
+		titles[name] = label
+	}
+}
+
+// นามสกุลไฟล์ของรูปปกตาม media type
+var coverExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// บันทึกรูปปกลง dir ครั้งเดียวต่อเล่ม เพื่อใช้ใส่ใน metadata ของไฟล์เสียง
+func (b *bookInfo) saveCover(dir, name string) error {
+	if b.Cover == nil || b.CoverPath != "" {
+		return nil
+	}
+	ext, ok := coverExtensions[b.CoverType]
+	if !ok {
+		return fmt.Errorf("ไม่รองรับรูปปกชนิด %s", b.CoverType)
+	}
+	coverPath := filepath.Join(dir, name+"_cover"+ext)
+	err := os.WriteFile(coverPath, b.Cover, 0644)
+	if err != nil {
+		return err
+	}
+	b.CoverPath = coverPath
+	return nil
+}
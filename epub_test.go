@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// บีบอัด fixture ใน testdata เป็นไฟล์ EPUB (mimetype ต้องเป็นไฟล์แรกและไม่บีบอัด)
+func buildEPUB(t *testing.T, dir string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	mimetype, err := os.ReadFile(filepath.Join(dir, "mimetype"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(mimetype)
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil || name == "mimetype" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(filepath.ToSlash(name))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), filepath.Base(dir)+".epub")
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReadEPUB3(t *testing.T) {
+	book, chapters, err := readEPUB(buildEPUB(t, "testdata/epub3"), markupPhrases{})
+	if err != nil {
+		t.Fatalf("readEPUB: %v", err)
+	}
+
+	if book.Title != "ตำนาน กระบี่ทดสอบ" || book.Author != "นักเขียน หนึ่ง, นักเขียน สอง" {
+		t.Errorf("book = %q by %q", book.Title, book.Author)
+	}
+	cover, _ := os.ReadFile("testdata/epub3/OEBPS/Images/cover.png")
+	if !bytes.Equal(book.Cover, cover) || book.CoverType != "image/png" {
+		t.Errorf("cover = %d bytes %q, want cover.png", len(book.Cover), book.CoverType)
+	}
+
+	// ลำดับตาม spine ไม่ใช่ manifest, ข้าม nav (ไม่มีข้อความหลังตัด <nav>) และ notes ที่ linear="no"
+	// ชื่อบทจากสารบัญ toc (ไม่ใช่ landmarks) หรือหัวข้อต้นบทเมื่อไม่มีในสารบัญ
+	want := []epubChapter{
+		{Href: "OEBPS/Text/chapter1.xhtml", Title: "บทที่ 1 การเริ่มต้น", Text: "บทที่ 1 การเริ่มต้น\nเขาเดินออกไปจากบ้าน\nและไม่กลับมาอีกเลย"},
+		{Href: "OEBPS/Text/chapter 2.xhtml", Title: "บทที่ 2 ศิษย์ใหม่", Text: "บทที่ 2\nศิษย์ฝึกวิชาดาบทุกวัน"},
+		{Href: "OEBPS/Text/chapter3.xhtml", Title: "บทส่งท้าย", Text: "บทส่งท้าย\nจบบริบูรณ์"},
+	}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Errorf("chapters =\n%+v\nwant\n%+v", chapters, want)
+	}
+}
+
+func TestReadEPUB2(t *testing.T) {
+	book, chapters, err := readEPUB(buildEPUB(t, "testdata/epub2"), markupPhrases{})
+	if err != nil {
+		t.Fatalf("readEPUB: %v", err)
+	}
+
+	if book.Title != "Old Format Book" || book.Author != "Jane Writer" {
+		t.Errorf("book = %q by %q", book.Title, book.Author)
+	}
+	// รูปปกจาก <meta name="cover">
+	cover, _ := os.ReadFile("testdata/epub2/OEBPS/cover.png")
+	if !bytes.Equal(book.Cover, cover) || book.CoverType != "image/png" {
+		t.Errorf("cover = %d bytes %q, want cover.png", len(book.Cover), book.CoverType)
+	}
+
+	// หน้าปกที่ linear="no" ถูกข้าม, ชื่อบทจาก navPoint แรกของแต่ละไฟล์ใน NCX
+	var hrefs, titles []string
+	for _, c := range chapters {
+		hrefs = append(hrefs, c.Href)
+		titles = append(titles, c.Title)
+	}
+	if want := []string{"OEBPS/part2.html", "OEBPS/part1.html"}; !reflect.DeepEqual(hrefs, want) {
+		t.Errorf("spine = %q, want %q", hrefs, want)
+	}
+	if want := []string{"Part Two", "Part One"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles = %q, want %q", titles, want)
+	}
+
+	// HTML ที่ไม่ใช่ XHTML: tag ไม่ปิด, &nbsp; และ < ที่ไม่ใช่ tag
+	if want := "Part One\nIt was a dark night.\nThen x < y happened.\nSection Two\nThe end of part one."; chapters[1].Text != want {
+		t.Errorf("part1 text = %q, want %q", chapters[1].Text, want)
+	}
+}
+
+func TestReadEPUBMissingOPF(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "mimetype"), []byte("application/epub+zip"), 0644)
+	if _, _, err := readEPUB(buildEPUB(t, dir), markupPhrases{}); err == nil {
+		t.Error("readEPUB without container.xml succeeded, want error")
+	}
+}
+
+func TestParseHTMLSkipsNonText(t *testing.T) {
+	html := `<html><head><title>ไม่อ่าน</title><style>p { color: red }</style></head>
+<body>
+<nav epub:type="toc"><ol><li><a href="a.xhtml">สารบัญ</a></li></ol></nav>
+<h1>หัวข้อ</h1>
+<script type="text/javascript">if (a < b && c) { alert("ไม่อ่าน"); }</script>
+<p>ย่อหน้า <b>หนึ่ง</b></p>
+<style>
+  .x { content: "<p>ไม่อ่าน</p>"; }
+</style>
+<p>ย่อหน้า<ruby>สอง<rt>ไม่อ่าน</rt></ruby></p>
+<pre>code()</pre>
+<p><img src="a.png" alt="แผนที่"/></p>
+</body></html>`
+
+	doc, err := parseHTML([]byte(html), markupPhrases{Code: "มีโค้ด", Image: "รูป {alt}"})
+	if err != nil {
+		t.Fatalf("parseHTML: %v", err)
+	}
+	text := stripEmphasis(doc.String())
+	if strings.Contains(text, "ไม่อ่าน") || strings.Contains(text, "สารบัญ") {
+		t.Errorf("text contains script, style, head, nav or rt: %q", text)
+	}
+	if want := "หัวข้อ\nย่อหน้า หนึ่ง\nย่อหน้าสอง\nมีโค้ด\nรูป แผนที่"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+	if doc.Title != "หัวข้อ" {
+		t.Errorf("title = %q, want หัวข้อ", doc.Title)
+	}
+}
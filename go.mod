@@ -2,7 +2,10 @@ module k-tts
 
 go 1.25.0
 
-require cloud.google.com/go/texttospeech v1.13.0
+require (
+	cloud.google.com/go/texttospeech v1.13.0
+	google.golang.org/grpc v1.72.0
+)
 
 require (
 	cloud.google.com/go v0.120.0 // indirect
@@ -32,6 +35,5 @@ require (
 	google.golang.org/api v0.231.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20250428153025-10db94c68c34 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250425173222-7b384671a197 // indirect
-	google.golang.org/grpc v1.72.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
 )
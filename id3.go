@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// tag ID3v2.4 ของไฟล์ MP3 หนึ่งบท
+type id3Tag struct {
+	Title      string
+	Album      string
+	Artist     string
+	Track      int
+	TrackTotal int // จำนวนบททั้งหมด (0 = ไม่ระบุ)
+	Genre      string
+	Cover      string // ไฟล์รูปปก (ว่าง = ไม่มี)
+	Synthesis  string // engine และเสียงที่ใช้ เก็บในเฟรม TXXX "TTS" (ว่าง = ไม่มี)
+}
+
+// ตัวเลขแบบ synchsafe ของ ID3v2.4 (ใช้ 7 bit ต่อ byte)
+func synchsafe(n int) []byte {
+	return []byte{byte(n>>21) & 0x7f, byte(n>>14) & 0x7f, byte(n>>7) & 0x7f, byte(n) & 0x7f}
+}
+
+// อ่านตัวเลข synchsafe 4 byte
+func readSynchsafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+// เฟรมหนึ่งเฟรม: ID 4 ตัวอักษร ขนาด synchsafe และ flags ว่าง
+func id3Frame(id string, body []byte) []byte {
+	frame := append([]byte(id), synchsafe(len(body))...)
+	frame = append(frame, 0, 0)
+	return append(frame, body...)
+}
+
+// เฟรมข้อความ (T***) เข้ารหัส UTF-8
+func id3TextFrame(id, value string) []byte {
+	return id3Frame(id, append([]byte{3}, value...))
+}
+
+// เฟรม TXXX: คำอธิบายและค่าคั่นด้วย null
+func id3UserTextFrame(description, value string) []byte {
+	body := append([]byte{3}, description...)
+	body = append(body, 0)
+	return id3Frame("TXXX", append(body, value...))
+}
+
+// เฟรม APIC: รูปปกหน้า (picture type 3) ไม่มีคำอธิบาย
+func id3PictureFrame(data []byte) []byte {
+	mime := http.DetectContentType(data)
+	body := append([]byte{3}, mime...)
+	body = append(body, 0, 3, 0)
+	return id3Frame("APIC", append(body, data...))
+}
+
+// สร้าง tag ID3v2.4 ทั้งก้อน (header + เฟรม)
+func (t id3Tag) encode() ([]byte, error) {
+	var frames []byte
+	for _, text := range [][2]string{{"TIT2", t.Title}, {"TALB", t.Album}, {"TPE1", t.Artist}, {"TCON", t.Genre}} {
+		if text[1] != "" {
+			frames = append(frames, id3TextFrame(text[0], text[1])...)
+		}
+	}
+	if t.Track > 0 {
+		track := fmt.Sprint(t.Track)
+		if t.TrackTotal > 0 {
+			track = fmt.Sprintf("%d/%d", t.Track, t.TrackTotal)
+		}
+		frames = append(frames, id3TextFrame("TRCK", track)...)
+	}
+	if t.Synthesis != "" {
+		frames = append(frames, id3UserTextFrame("TTS", t.Synthesis)...)
+	}
+	if t.Cover != "" {
+		data, err := os.ReadFile(t.Cover)
+		if err != nil {
+			return nil, fmt.Errorf("ไม่สามารถอ่านรูปปก: %v", err)
+		}
+		frames = append(frames, id3PictureFrame(data)...)
+	}
+
+	header := append([]byte("ID3"), 4, 0, 0)
+	header = append(header, synchsafe(len(frames))...)
+	return append(header, frames...), nil
+}
+
+// ความยาวของ tag ID3v2 ที่อยู่ต้นไฟล์ (0 = ไม่มี)
+func id3v2Length(data []byte) int {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return 0
+	}
+	length := 10 + readSynchsafe(data[6:10])
+	if data[5]&0x10 != 0 { // มี footer
+		length += 10
+	}
+	if length > len(data) {
+		return 0
+	}
+	return length
+}
+
+// เขียน tag ID3v2.4 ลงไฟล์ MP3 แทน tag เดิมที่ ffmpeg ใส่ไว้
+// ต้องเรียกหลัง ffmpeg ทำงานครบทุกขั้น เพราะ ffmpeg เขียน tag ของตัวเองทับทุกครั้ง
+func writeID3Tag(path string, tag id3Tag) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	encoded, err := tag.encode()
+	if err != nil {
+		return err
+	}
+
+	// ตัด tag ID3v2 ทุกชุดที่ต้นไฟล์และ ID3v1 ท้ายไฟล์
+	for n := id3v2Length(data); n > 0; n = id3v2Length(data) {
+		data = data[n:]
+	}
+	if len(data) >= 128 && bytes.HasPrefix(data[len(data)-128:], []byte("TAG")) {
+		data = data[:len(data)-128]
+	}
+
+	tempFile := path + ".tag.tmp"
+	err = os.WriteFile(tempFile, append(encoded, data...), 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempFile, path)
+	if err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+	return nil
+}
+
+// tag ของไฟล์เสียงหนึ่งบท: ชื่อบท (หรือชื่อไฟล์) ชื่อหนังสือและผู้แต่งจาก config หรือ EPUB
+// หมายเลข track จากลำดับงาน และ engine กับเสียงที่ใช้สร้าง
+func (c *Config) chapterTag(job TTSJob, engine string) id3Tag {
+	meta := c.bookMetadata([]TTSJob{job})
+	synthesis := engine
+	if voice := job.Voice.Name; voice != "" {
+		synthesis += " / " + voice
+	} else if job.Voice.Language != "" {
+		synthesis += " / " + job.Voice.Language
+	}
+	return id3Tag{
+		Title:      job.chapterName(),
+		Album:      meta.Album,
+		Artist:     meta.Artist,
+		Track:      job.ID,
+		TrackTotal: job.Total,
+		Genre:      "Audiobook",
+		Cover:      meta.Cover,
+		Synthesis:  synthesis,
+	}
+}
+
+// tag เดียวกันในรูปแบบ -metadata ของ ffmpeg สำหรับไฟล์ที่ไม่ใช่ MP3 (ไม่รวมรูปปก)
+func (t id3Tag) ffmpegArgs() []string {
+	track := ""
+	if t.Track > 0 {
+		track = fmt.Sprint(t.Track)
+		if t.TrackTotal > 0 {
+			track = fmt.Sprintf("%d/%d", t.Track, t.TrackTotal)
+		}
+	}
+	var args []string
+	for _, tag := range [][2]string{{"title", t.Title}, {"album", t.Album}, {"artist", t.Artist}, {"track", track}, {"genre", t.Genre}, {"comment", t.Synthesis}} {
+		if tag[1] != "" {
+			args = append(args, "-metadata", tag[0]+"="+tag[1])
+		}
+	}
+	return args
+}
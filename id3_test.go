@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// เฟรมใน tag ID3v2.4 ตามลำดับ
+type testID3Frame struct {
+	ID   string
+	Body []byte
+}
+
+// แยก tag ID3v2.4 ที่ encode แล้วเป็นเฟรม โดยตรวจ header และขนาดแบบ synchsafe
+func parseTestID3(t *testing.T, data []byte) []testID3Frame {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte{'I', 'D', '3', 4, 0, 0}) {
+		t.Fatalf("header = % x, want ID3v2.4 without flags", data[:min(len(data), 6)])
+	}
+	for _, b := range data[6:10] {
+		if b&0x80 != 0 {
+			t.Fatalf("tag size % x is not synchsafe", data[6:10])
+		}
+	}
+	if size := readSynchsafe(data[6:10]); size != len(data)-10 {
+		t.Fatalf("tag size = %d, want %d", size, len(data)-10)
+	}
+
+	var frames []testID3Frame
+	for rest := data[10:]; len(rest) > 0; {
+		if len(rest) < 10 {
+			t.Fatalf("truncated frame header % x", rest)
+		}
+		for _, b := range rest[4:8] {
+			if b&0x80 != 0 {
+				t.Fatalf("%s frame size % x is not synchsafe", rest[:4], rest[4:8])
+			}
+		}
+		size := readSynchsafe(rest[4:8])
+		if rest[8] != 0 || rest[9] != 0 {
+			t.Errorf("%s frame flags = % x, want none", rest[:4], rest[8:10])
+		}
+		if 10+size > len(rest) {
+			t.Fatalf("%s frame size %d exceeds the tag", rest[:4], size)
+		}
+		frames = append(frames, testID3Frame{string(rest[:4]), rest[10 : 10+size]})
+		rest = rest[10+size:]
+	}
+	return frames
+}
+
+func TestSynchsafe(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{0, []byte{0, 0, 0, 0}},
+		{127, []byte{0, 0, 0, 0x7f}},
+		{128, []byte{0, 0, 1, 0}},
+		{255, []byte{0, 0, 1, 0x7f}},
+		{1 << 20, []byte{0, 0x40, 0, 0}},
+		{1<<28 - 1, []byte{0x7f, 0x7f, 0x7f, 0x7f}},
+	}
+	for _, tt := range tests {
+		got := synchsafe(tt.n)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("synchsafe(%d) = % x, want % x", tt.n, got, tt.want)
+		}
+		if back := readSynchsafe(got); back != tt.n {
+			t.Errorf("readSynchsafe(% x) = %d, want %d", got, back, tt.n)
+		}
+	}
+}
+
+// รูป PNG ขนาดเล็กที่ http.DetectContentType รู้จัก
+var testPNG = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 200)...)
+
+func TestID3TagEncode(t *testing.T) {
+	cover := filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(cover, testPNG, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// ชื่อบทยาวเกิน 127 byte เพื่อให้ขนาดเฟรมต้องใช้ synchsafe หลาย byte
+	title := strings.Repeat("บทที่หนึ่ง ", 10)
+	tag := id3Tag{
+		Title:      title,
+		Album:      "ดาบพิฆาต",
+		Artist:     "ผู้แต่ง",
+		Track:      3,
+		TrackTotal: 12,
+		Genre:      "Audiobook",
+		Cover:      cover,
+		Synthesis:  "cloud / th-TH-Neural2-C",
+	}
+	data, err := tag.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := func(value string) []byte { return append([]byte{3}, value...) }
+	picture := append([]byte("\x03image/png\x00\x03\x00"), testPNG...)
+	want := []testID3Frame{
+		{"TIT2", text(title)},
+		{"TALB", text("ดาบพิฆาต")},
+		{"TPE1", text("ผู้แต่ง")},
+		{"TCON", text("Audiobook")},
+		{"TRCK", text("3/12")},
+		{"TXXX", text("TTS\x00cloud / th-TH-Neural2-C")},
+		{"APIC", picture},
+	}
+	if got := parseTestID3(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("frames =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestID3TagEncodeMinimal(t *testing.T) {
+	// ช่องว่างไม่เขียนเฟรม และ track ที่ไม่รู้จำนวนบทเขียนเฉพาะหมายเลข
+	data, err := id3Tag{Title: "บทนำ", Track: 1}.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []testID3Frame{
+		{"TIT2", append([]byte{3}, "บทนำ"...)},
+		{"TRCK", []byte("\x031")},
+	}
+	if got := parseTestID3(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("frames = %q, want %q", got, want)
+	}
+
+	if _, err := (id3Tag{Cover: filepath.Join(t.TempDir(), "missing.jpg")}).encode(); err == nil {
+		t.Error("encode with a missing cover succeeded, want error")
+	}
+}
+
+func TestWriteID3TagReplacesTags(t *testing.T) {
+	audio := []byte("\xff\xfb\x90\x00 mp3 frames")
+
+	// tag v2.3 จาก ffmpeg, tag v2.4 ที่มี footer ซ้อนกัน และ tag v1 ท้ายไฟล์
+	v23 := append([]byte("ID3\x03\x00\x00"), synchsafe(5)...)
+	v23 = append(v23, "xxxxx"...)
+	v24 := append([]byte("ID3\x04\x00\x10"), synchsafe(3)...)
+	v24 = append(v24, "yyy3DI\x04\x00\x10"...)
+	v24 = append(v24, synchsafe(3)...)
+	v1 := append([]byte("TAG"), make([]byte, 125)...)
+
+	path := filepath.Join(t.TempDir(), "001.mp3")
+	original := bytes.Join([][]byte{v23, v24, audio, v1}, nil)
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tag := id3Tag{Title: "บทที่ 1", Album: "ดาบพิฆาต", Track: 1}
+	encoded, err := tag.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// เขียนซ้ำต้องได้ไฟล์เดิม ไม่มี tag ซ้อน
+	for i := 0; i < 2; i++ {
+		if err := writeID3Tag(path, tag); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := append(append([]byte{}, encoded...), audio...); !bytes.Equal(got, want) {
+			t.Fatalf("pass %d: file =\n%q\nwant\n%q", i+1, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".tag.tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestID3TagFFmpegArgs(t *testing.T) {
+	tag := id3Tag{Title: "บทที่ 1", Artist: "ผู้แต่ง", Track: 2, TrackTotal: 5, Synthesis: "translate / th"}
+	want := []string{
+		"-metadata", "title=บทที่ 1",
+		"-metadata", "artist=ผู้แต่ง",
+		"-metadata", "track=2/5",
+		"-metadata", "comment=translate / th",
+	}
+	if got := tag.ffmpegArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ffmpegArgs = %q, want %q", got, want)
+	}
+}
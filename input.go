@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// บทหนึ่งจากไฟล์ input: ไฟล์ข้อความหนึ่งไฟล์ หรือหนึ่ง spine item ของ EPUB
+type inputChapter struct {
+	FilePath string // path ของไฟล์ต้นฉบับ (บทของ EPUB = path ของ EPUB/ลำดับ_ชื่อไฟล์ในเล่ม)
+	BaseName string // ชื่อไฟล์ output ที่ไม่มีนามสกุล และ key ของ chapters ใน config
+	Text     string
+	SSML     bool
+	Title    string    // ชื่อบทจากสารบัญหรือหัวข้อของ markup (ว่าง = หาจากบรรทัดแรก)
+	Book     *bookInfo // หนังสือที่บทนี้อยู่ (nil = ไฟล์ข้อความเดี่ยว)
+	Encoding string    // การเข้ารหัสของไฟล์ต้นฉบับ
+}
+
+// อ่านไฟล์ input เป็นบท: ไฟล์ .epub ได้หลายบทตาม spine ไฟล์อื่นได้หนึ่งบท
+// ไฟล์ Markdown และ HTML ถูกแยกเป็นย่อหน้า โดยหัวข้อแรกเป็นชื่อบท
+func readInputChapters(cfg *Config, file, encoding string) ([]inputChapter, error) {
+	baseName := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	ext := strings.ToLower(filepath.Ext(file))
+
+	if ext == ".epub" {
+		book, chapters, err := readEPUB(file, cfg.markupPhrases())
+		if err != nil {
+			return nil, err
+		}
+		result := make([]inputChapter, len(chapters))
+		for i, ch := range chapters {
+			result[i] = inputChapter{
+				FilePath: filepath.Join(file, fmt.Sprintf("%03d_%s", i+1, path.Base(ch.Href))),
+				BaseName: fmt.Sprintf("%s_%03d", baseName, i+1),
+				Text:     ch.Text,
+				Title:    ch.Title,
+				Book:     book,
+				Encoding: encodingUTF8,
+			}
+		}
+		return result, nil
+	}
+
+	text, used, err := readTextFile(file, encoding)
+	if err != nil {
+		return nil, err
+	}
+	chapter := inputChapter{
+		FilePath: file,
+		BaseName: baseName,
+		Text:     text,
+		SSML:     ext == ".ssml",
+		Encoding: used,
+	}
+
+	var doc markupText
+	switch ext {
+	case ".md", ".markdown":
+		doc = parseMarkdown(text, cfg.markupPhrases())
+	case ".html", ".htm", ".xhtml":
+		doc, err = parseHTML([]byte(text), cfg.markupPhrases())
+		if err != nil {
+			return nil, fmt.Errorf("HTML ไม่ถูกต้อง: %v", err)
+		}
+	default:
+		return []inputChapter{chapter}, nil
+	}
+	chapter.Text = doc.String()
+	chapter.Title = doc.Title
+	return []inputChapter{chapter}, nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// อักษรที่ใช้แยกช่วงภาษา
+const (
+	scriptThai  = "thai"
+	scriptLatin = "latin"
+	scriptCJK   = "cjk"
+)
+
+// เสียงที่ใช้อ่านข้อความของอักษรหนึ่ง
+type ScriptVoice struct {
+	Voice    string `json:"voice"`    // ชื่อเสียงของ Google Cloud TTS
+	Language string `json:"language"` // รหัสภาษา เช่น "en-US" (ใช้เป็น tl ของ Translate TTS ด้วย)
+	Gender   string `json:"gender"`
+}
+
+// อักษรของตัวอักษร (ว่าง = ตัวเลข ช่องว่าง หรือเครื่องหมายที่ใช้ได้ทุกภาษา)
+func runeScript(r rune) string {
+	switch {
+	case isThaiRune(r):
+		if unicode.IsLetter(r) || unicode.IsMark(r) {
+			return scriptThai
+		}
+		return ""
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return scriptCJK
+	case unicode.Is(unicode.Latin, r):
+		return scriptLatin
+	}
+	return ""
+}
+
+// อักษรหลักของภาษา เช่น "th-TH" = thai
+func languageScript(language string) string {
+	lang := strings.ToLower(language)
+	switch {
+	case strings.HasPrefix(lang, "th"):
+		return scriptThai
+	case strings.HasPrefix(lang, "zh"), strings.HasPrefix(lang, "cmn"), strings.HasPrefix(lang, "yue"),
+		strings.HasPrefix(lang, "ja"), strings.HasPrefix(lang, "ko"):
+		return scriptCJK
+	}
+	return scriptLatin
+}
+
+// ช่วงข้อความที่อ่านด้วยเสียงเดียวกัน (ตำแหน่งเป็น index ของ rune)
+type languageRun struct {
+	start, end int
+	letters    int
+	script     string
+	voice      VoiceSettings
+}
+
+// แบ่งข้อความเป็นช่วงตามอักษร แล้วเลือกเสียงของแต่ละช่วง
+// ช่วงที่มีตัวอักษรน้อยกว่า minRun (เช่น คำยืมคำเดียว) จะรวมกับช่วงก่อนหน้าเพื่อไม่ให้สลับภาษาบ่อย
+// ตัวเลขและเครื่องหมายอยู่กับช่วงก่อนหน้า
+func languageRuns(runes []rune, minRun int, voiceOf func(script string) VoiceSettings) []languageRun {
+	var runs []languageRun
+	for i, r := range runes {
+		script := runeScript(r)
+		if len(runs) == 0 {
+			runs = append(runs, languageRun{start: i, script: script})
+		}
+		last := &runs[len(runs)-1]
+		if script != "" && last.script != "" && script != last.script {
+			runs = append(runs, languageRun{start: i, script: script})
+			last = &runs[len(runs)-1]
+		}
+		if last.script == "" {
+			last.script = script
+		}
+		if script != "" {
+			last.letters++
+		}
+		last.end = i + 1
+	}
+
+	// รวมช่วงสั้นเข้ากับช่วงก่อนหน้า (ช่วงแรกรวมกับช่วงถัดไป)
+	for i := 0; i < len(runs) && len(runs) > 1; {
+		if runs[i].letters >= minRun {
+			i++
+			continue
+		}
+		if i == 0 {
+			runs[1].start = runs[0].start
+			runs[1].letters += runs[0].letters
+			runs = runs[1:]
+		} else {
+			runs[i-1].end = runs[i].end
+			runs[i-1].letters += runs[i].letters
+			runs = append(runs[:i], runs[i+1:]...)
+		}
+	}
+
+	// ช่วงติดกันที่ได้เสียงเดียวกันรวมเป็นช่วงเดียว
+	var merged []languageRun
+	for _, run := range runs {
+		run.voice = voiceOf(run.script)
+		if n := len(merged); n > 0 && merged[n-1].voice == run.voice {
+			merged[n-1].end = run.end
+			merged[n-1].letters += run.letters
+			continue
+		}
+		merged = append(merged, run)
+	}
+	return merged
+}
+
+// เสียงของอักษร: อักษรหลักของบทใช้เสียงของบท อักษรอื่นใช้ script_voices หากกำหนดไว้
+func (c *Config) scriptVoiceFunc(base VoiceSettings) func(script string) VoiceSettings {
+	baseScript := languageScript(base.Language)
+	return func(script string) VoiceSettings {
+		if script == "" || script == baseScript {
+			return base
+		}
+		sv, ok := c.ScriptVoices[script]
+		if !ok {
+			return base
+		}
+		return VoiceSettings{Name: sv.Voice, Language: sv.Language, Gender: strings.ToUpper(sv.Gender)}
+	}
+}
+
+// เสียงทั้งหมดที่อาจใช้กับบทที่มีเสียงหลักนี้ (สำหรับตรวจสอบก่อนเริ่ม)
+func (c *Config) scriptVoices(base VoiceSettings) []VoiceSettings {
+	voiceOf := c.scriptVoiceFunc(base)
+	var voices []VoiceSettings
+	for _, script := range []string{scriptThai, scriptLatin, scriptCJK} {
+		if voice := voiceOf(script); voice != base {
+			voices = append(voices, voice)
+		}
+	}
+	return voices
+}
+
+// แบ่งคำขอตามช่วงภาษา โดยแต่ละช่วงใช้เสียง (หรือ tl ของ Translate TTS) ของภาษานั้น
+func splitRequestsByLanguage(cfg *Config, reqs []SynthesisRequest) ([]SynthesisRequest, error) {
+	var result []SynthesisRequest
+	for _, req := range reqs {
+		if req.Pause > 0 {
+			result = append(result, req)
+			continue
+		}
+		voiceOf := cfg.scriptVoiceFunc(req.Voice)
+
+		if !req.SSML {
+			runes := []rune(req.Text)
+			runs := languageRuns(runes, cfg.MinLanguageRun, voiceOf)
+			if len(runs) <= 1 {
+				result = append(result, req)
+				continue
+			}
+			for _, run := range runs {
+				text := strings.TrimSpace(string(runes[run.start:run.end]))
+				if text != "" {
+					result = append(result, SynthesisRequest{Text: text, Voice: run.voice, Display: req.Display})
+				}
+			}
+			continue
+		}
+
+		// คำขอที่เป็น SSML มาจาก Cloud TTS จึงใช้ขีดจำกัดขนาดของ Cloud TTS
+		parts, err := splitSSMLByLanguage(req.Text, cfg.MinLanguageRun, cloudTTSMaxInputBytes, voiceOf)
+		if err != nil {
+			return nil, fmt.Errorf("ไม่สามารถแบ่ง SSML ตามภาษา: %v", err)
+		}
+		if len(parts) <= 1 {
+			result = append(result, req)
+			continue
+		}
+		for i := range parts {
+			parts[i].Display = req.Display
+		}
+		result = append(result, parts...)
+	}
+	return result, nil
+}
+
+// แบ่งเอกสาร SSML ตามช่วงภาษา tag ที่เปิดค้างจะถูกปิดและเปิดใหม่ในเอกสารถัดไป
+// ข้อความใน element ที่ห้ามแบ่ง (เช่น <say-as>, <phoneme>) อ่านด้วยเสียงของช่วงที่อยู่
+// เอกสารที่ได้แต่ละส่วนไม่เกิน maxBytes
+func splitSSMLByLanguage(doc string, minRun, maxBytes int, voiceOf func(string) VoiceSettings) ([]SynthesisRequest, error) {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return nil, err
+	}
+	units := ssmlUnits(tokens)
+
+	// ข้อความทั้งหมดของเอกสารสำหรับหาช่วงภาษา
+	var text []rune
+	texts := make([][]rune, len(units))
+	for i, u := range units {
+		if u.kind == ssmlText {
+			texts[i] = []rune(html.UnescapeString(u.raw))
+			text = append(text, texts[i]...)
+		}
+	}
+	runs := languageRuns(text, minRun, voiceOf)
+	if len(runs) <= 1 {
+		return nil, nil
+	}
+
+	var parts []SynthesisRequest
+	var stack []ssmlToken
+	var body strings.Builder
+	hasContent := false
+	current := runs[0].voice
+	flush := func() {
+		if hasContent {
+			for i := len(stack) - 1; i >= 0; i-- {
+				body.WriteString("</" + stack[i].name + ">")
+			}
+			parts = append(parts, SynthesisRequest{Text: "<speak>" + body.String() + "</speak>", SSML: true, Voice: current})
+		}
+		body.Reset()
+		for _, t := range stack {
+			body.WriteString(t.raw)
+		}
+		hasContent = false
+	}
+
+	pos, run := 0, 0
+	for i, u := range units {
+		switch u.kind {
+		case ssmlOpen:
+			body.WriteString(u.raw)
+			stack = append(stack, u)
+		case ssmlClose:
+			body.WriteString(u.raw)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ssmlSelfClosing:
+			body.WriteString(u.raw)
+			hasContent = hasContent || ssmlAtomicElements[u.name]
+		case ssmlText:
+			for start := 0; start < len(texts[i]); {
+				for runs[run].end <= pos+start {
+					run++
+				}
+				end := min(len(texts[i]), runs[run].end-pos)
+				piece := string(texts[i][start:end])
+				if runs[run].voice != current && strings.TrimSpace(piece) != "" {
+					flush()
+					current = runs[run].voice
+				}
+				body.WriteString(escapeSSML(piece))
+				hasContent = hasContent || strings.TrimSpace(piece) != ""
+				start = end
+			}
+			pos += len(texts[i])
+		}
+	}
+	flush()
+
+	// ข้อความที่ escape ใหม่ (เช่น ' เป็น &apos;) อาจทำให้ส่วนยาวกว่าเอกสารเดิม จึงแบ่งซ้ำตามขนาด
+	var result []SynthesisRequest
+	for _, part := range parts {
+		if len(part.Text) <= maxBytes {
+			result = append(result, part)
+			continue
+		}
+		docs, err := splitSSML(part.Text, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range docs {
+			result = append(result, SynthesisRequest{Text: d, SSML: true, Voice: part.Voice})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	testThaiVoice    = VoiceSettings{Name: "th-TH-Neural2-C", Language: "th-TH"}
+	testEnglishVoice = VoiceSettings{Name: "en-US-Neural2-C", Language: "en-US"}
+)
+
+// ข้อความของแต่ละช่วงภาษาพร้อมเสียง
+func runTexts(runes []rune, runs []languageRun) []string {
+	var texts []string
+	for _, run := range runs {
+		texts = append(texts, run.voice.Language+":"+strings.TrimSpace(string(runes[run.start:run.end])))
+	}
+	return texts
+}
+
+func TestLanguageRuns(t *testing.T) {
+	cfg := defaultConfig()
+	voiceOf := cfg.scriptVoiceFunc(testThaiVoice)
+	tests := []struct {
+		in     string
+		minRun int
+		want   []string
+	}{
+		{"เขาพูดว่า Hello world แล้วยิ้ม", 3, []string{"th-TH:เขาพูดว่า", "en-US:Hello world", "th-TH:แล้วยิ้ม"}},
+		// ตัวเลขและเครื่องหมายอยู่กับช่วงก่อนหน้า
+		{"ราคา 100 บาท, Total 100 USD.", 3, []string{"th-TH:ราคา 100 บาท,", "en-US:Total 100 USD."}},
+		// คำยืมที่สั้นกว่า minRun อ่านด้วยเสียงไทย
+		{"เขาใช้ iPad ทุกวัน", 5, []string{"th-TH:เขาใช้ iPad ทุกวัน"}},
+		// ช่วงแรกที่สั้นรวมกับช่วงถัดไป
+		{"OK เข้าใจแล้ว", 3, []string{"th-TH:OK เข้าใจแล้ว"}},
+		{"Chapter One บทที่หนึ่ง", 3, []string{"en-US:Chapter One", "th-TH:บทที่หนึ่ง"}},
+		{"Hello world", 3, []string{"en-US:Hello world"}},
+	}
+	for _, tt := range tests {
+		runes := []rune(tt.in)
+		if got := runTexts(runes, languageRuns(runes, tt.minRun, voiceOf)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("languageRuns(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitRequestsByLanguageText(t *testing.T) {
+	cfg := defaultConfig()
+	reqs := []SynthesisRequest{
+		{Text: "เขาพูดว่า Hello world แล้วยิ้ม", Voice: testThaiVoice, Display: "เขาพูดว่า Hello world แล้วยิ้ม"},
+		{Pause: 700},
+		{Text: "ไม่มีภาษาอื่น", Voice: testThaiVoice},
+	}
+	got, err := splitRequestsByLanguage(&cfg, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	display := reqs[0].Display
+	want := []SynthesisRequest{
+		{Text: "เขาพูดว่า", Voice: testThaiVoice, Display: display},
+		{Text: "Hello world", Voice: testEnglishVoice, Display: display},
+		{Text: "แล้วยิ้ม", Voice: testThaiVoice, Display: display},
+		{Pause: 700},
+		{Text: "ไม่มีภาษาอื่น", Voice: testThaiVoice},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitRequestsByLanguage =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSplitSSMLByLanguage(t *testing.T) {
+	cfg := defaultConfig()
+	doc := `<speak><p><prosody rate="slow">เขาพูดว่า <emphasis>Hello world</emphasis> แล้ว<sub alias="ยิ้ม">😊</sub></prosody></p><p>The End</p></speak>`
+	got, err := splitSSMLByLanguage(doc, 3, cloudTTSMaxInputBytes, cfg.scriptVoiceFunc(testThaiVoice))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tag ที่เปิดค้างถูกปิดท้ายส่วนและเปิดใหม่ในส่วนถัดไป
+	want := []SynthesisRequest{
+		{Text: `<speak><p><prosody rate="slow">เขาพูดว่า <emphasis></emphasis></prosody></p></speak>`, SSML: true, Voice: testThaiVoice},
+		{Text: `<speak><p><prosody rate="slow"><emphasis>Hello world</emphasis> </prosody></p></speak>`, SSML: true, Voice: testEnglishVoice},
+		{Text: `<speak><p><prosody rate="slow">แล้ว<sub alias="ยิ้ม">😊</sub></prosody></p><p></p></speak>`, SSML: true, Voice: testThaiVoice},
+		{Text: `<speak><p>The End</p></speak>`, SSML: true, Voice: testEnglishVoice},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSSMLByLanguage =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSplitSSMLByLanguageStaysWithinLimit(t *testing.T) {
+	cfg := defaultConfig()
+	voiceOf := cfg.scriptVoiceFunc(testThaiVoice)
+
+	// เครื่องหมายคำพูดในข้อความ SSML ไม่ต้อง escape แต่จะกลายเป็น &quot; เมื่อแบ่งตามภาษา
+	// เอกสารที่ไม่เกินขีดจำกัดจึงให้ช่วงภาษาไทยที่ยาวเกินหากไม่แบ่งซ้ำ
+	quoted := strings.Repeat(`ก"`, 1100)
+	doc := `<speak><prosody rate="slow">` + quoted + ` He said yes ` + quoted[:400] + `</prosody></speak>`
+	if len(doc) > cloudTTSMaxInputBytes {
+		t.Fatalf("test document is %d bytes", len(doc))
+	}
+	parts, err := splitSSMLByLanguage(doc, 3, cloudTTSMaxInputBytes, voiceOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text strings.Builder
+	var voices []string
+	for _, part := range parts {
+		if len(part.Text) > cloudTTSMaxInputBytes {
+			t.Errorf("%s part is %d bytes, limit %d", part.Voice.Language, len(part.Text), cloudTTSMaxInputBytes)
+		}
+		if _, err := normalizeSSML(part.Text); err != nil {
+			t.Errorf("part is not balanced: %v", err)
+		}
+		text.WriteString(ssmlToText(part.Text))
+		voices = append(voices, part.Voice.Language)
+	}
+	if want := []string{"th-TH", "th-TH", "en-US", "th-TH"}; !reflect.DeepEqual(voices, want) {
+		t.Errorf("voices = %q, want %q", voices, want)
+	}
+	if got, want := strings.Join(strings.Fields(text.String()), ""), strings.Join(strings.Fields(ssmlToText(doc)), ""); got != want {
+		t.Errorf("parts do not keep the text of the document")
+	}
+}
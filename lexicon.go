@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// คำในพจนานุกรมการออกเสียง
+type lexiconEntry struct {
+	Word       string
+	Respelling string // คำอ่านที่เขียนใหม่ (ใช้กับทุก engine)
+	Alphabet   string // ระบบสัทอักษรของ Phoneme เช่น "ipa", "x-sampa"
+	Phoneme    string // คำอ่านแบบสัทอักษรสำหรับ <phoneme> ของ Cloud TTS
+
+	caseSensitive bool // คำที่มีตัวพิมพ์ใหญ่ต้องตรงทุกตัว คำตัวพิมพ์เล็กล้วนตรงได้ทุกแบบ
+	runes         int
+}
+
+// พจนานุกรมการออกเสียงของ project (แก้ชื่อตัวละครและคำที่แต่งขึ้นโดยไม่ต้องแก้ต้นฉบับ)
+type lexicon struct {
+	entries map[string]lexiconEntry // key = Word
+	byFirst map[rune][]lexiconEntry // เรียงจากยาวไปสั้น เพื่อให้ตรงแบบยาวที่สุดก่อน
+}
+
+func newLexicon() *lexicon {
+	return &lexicon{entries: map[string]lexiconEntry{}}
+}
+
+// อ่านไฟล์ lexicon รูปแบบ "คำ = คำอ่าน" หนึ่งคำต่อบรรทัด
+// คำอ่านขึ้นต้นด้วย "ipa:" หรือ "x-sampa:" คือสัทอักษร และคั่นคำอ่านหลายแบบด้วย "|"
+// เช่น "Qi = ชี่ | ipa:tɕʰi"
+func (l *lexicon) load(cleaner *textCleaner, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ไม่สามารถอ่าน lexicon %s: %v", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, value, ok := strings.Cut(line, "=")
+		// ใช้รูปแบบเดียวกับข้อความที่ทำความสะอาดแล้ว
+		if cleaned, kept := cleaner.cleanLine(word); kept {
+			word = cleaned
+		} else {
+			word = strings.Join(strings.Fields(word), " ")
+		}
+		if !ok || word == "" {
+			return fmt.Errorf("%s บรรทัด %d: ต้องอยู่ในรูปแบบ \"คำ = คำอ่าน\"", path, lineNo)
+		}
+
+		entry := lexiconEntry{Word: word}
+		for _, part := range strings.Split(value, "|") {
+			part = strings.TrimSpace(part)
+			alphabet, phoneme, isPhoneme := strings.Cut(part, ":")
+			alphabet = strings.ToLower(strings.TrimSpace(alphabet))
+			if isPhoneme && (alphabet == "ipa" || alphabet == "x-sampa") {
+				entry.Alphabet = alphabet
+				entry.Phoneme = strings.TrimSpace(phoneme)
+			} else if part != "" {
+				entry.Respelling = part
+			}
+		}
+		if entry.Respelling == "" && entry.Phoneme == "" {
+			return fmt.Errorf("%s บรรทัด %d: ไม่มีคำอ่านของ %q", path, lineNo, word)
+		}
+		l.add(entry)
+	}
+	return scanner.Err()
+}
+
+// เพิ่มหรือแทนที่คำ
+func (l *lexicon) add(entry lexiconEntry) {
+	entry.caseSensitive = strings.ToLower(entry.Word) != entry.Word
+	entry.runes = utf8.RuneCountInString(entry.Word)
+	l.entries[entry.Word] = entry
+	l.byFirst = nil
+}
+
+// สร้าง lexicon ใหม่จากหลายไฟล์ตามลำดับ (ไฟล์หลังทับคำเดียวกันในไฟล์ก่อน)
+func loadLexicons(cleaner *textCleaner, paths ...string) (*lexicon, error) {
+	l := newLexicon()
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		err := l.load(cleaner, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(l.entries) == 0 {
+		return nil, nil
+	}
+	// คำไทยใน lexicon (มักเป็นชื่อเฉพาะ) เป็นคำหนึ่งคำในการตัดคำด้วย เพื่อให้ขอบเขตคำตรงกับคำใน lexicon
+	// (โหลดก่อน worker เริ่มทำงานเช่นเดียวกับ --dictionary)
+	dict := defaultThaiDictionary()
+	for word := range l.entries {
+		if isThaiWord(word) {
+			dict.add(word)
+		}
+	}
+	// สร้าง index ไว้ก่อน เพื่อให้หลาย worker อ่านพร้อมกันได้โดยไม่ต้องล็อก
+	l.index()
+	return l, nil
+}
+
+// ข้อความภาษาไทยล้วนไม่มีช่องว่าง
+func isThaiWord(word string) bool {
+	for _, r := range word {
+		if !isThaiRune(r) {
+			return false
+		}
+	}
+	return word != ""
+}
+
+// จัดกลุ่มคำตามตัวอักษรแรก (ตัวพิมพ์เล็ก) เรียงจากยาวไปสั้น
+func (l *lexicon) index() map[rune][]lexiconEntry {
+	if l.byFirst != nil {
+		return l.byFirst
+	}
+	l.byFirst = map[rune][]lexiconEntry{}
+	for _, entry := range l.entries {
+		first, _ := utf8.DecodeRuneInString(entry.Word)
+		first = unicode.ToLower(first)
+		l.byFirst[first] = append(l.byFirst[first], entry)
+	}
+	for _, entries := range l.byFirst {
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].runes != entries[j].runes {
+				return entries[i].runes > entries[j].runes
+			}
+			return entries[i].Word < entries[j].Word
+		})
+	}
+	return l.byFirst
+}
+
+// ตัวอักษรละตินหรือตัวเลข (คำละตินต้องตรงทั้งคำ ไม่ตรงกลางคำอื่น)
+func isLatinWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// หาคำที่ตรงที่ตำแหน่ง i (ยาวที่สุดก่อน)
+// boundaries คือขอบเขตคำของ runes จาก defaultThaiDictionary
+func (l *lexicon) matchAt(runes []rune, boundaries []bool, i int) (lexiconEntry, bool) {
+	for _, entry := range l.index()[unicode.ToLower(runes[i])] {
+		end := i + entry.runes
+		if end > len(runes) {
+			continue
+		}
+		candidate := string(runes[i:end])
+		if entry.caseSensitive {
+			if candidate != entry.Word {
+				continue
+			}
+		} else if !strings.EqualFold(candidate, entry.Word) {
+			continue
+		}
+		// คำละตินต้องไม่อยู่ติดกับตัวอักษรละตินอื่น
+		if isLatinWordRune(runes[i]) && i > 0 && isLatinWordRune(runes[i-1]) {
+			continue
+		}
+		if isLatinWordRune(runes[end-1]) && end < len(runes) && isLatinWordRune(runes[end]) {
+			continue
+		}
+		// คำไทยต้องเริ่มและจบที่ขอบเขตคำ ไม่ตรงกลางคำอื่น เช่น "ตา" ใน "ตาย"
+		if isThaiRune(runes[i]) && !boundaries[i] {
+			continue
+		}
+		if isThaiRune(runes[end-1]) && !boundaries[end] {
+			continue
+		}
+		return entry, true
+	}
+	return lexiconEntry{}, false
+}
+
+// แทนที่คำด้วยผลจาก render โดยข้อความที่ไม่ตรงส่งผ่าน plain
+func (l *lexicon) replace(text string, render func(lexiconEntry, string) string, plain func(string) string) string {
+	runes := []rune(text)
+	boundaries := defaultThaiDictionary().boundaries(runes)
+	var sb strings.Builder
+	start := 0
+	for i := 0; i < len(runes); {
+		entry, ok := l.matchAt(runes, boundaries, i)
+		if !ok {
+			i++
+			continue
+		}
+		end := i + entry.runes
+		sb.WriteString(plain(string(runes[start:i])))
+		sb.WriteString(render(entry, string(runes[i:end])))
+		start, i = end, end
+	}
+	sb.WriteString(plain(string(runes[start:])))
+	return sb.String()
+}
+
+// แทนที่คำด้วยคำอ่าน (คำที่มีแต่สัทอักษรคงคำเดิมไว้)
+func (l *lexicon) apply(text string) string {
+	return l.replace(text, func(entry lexiconEntry, matched string) string {
+		if entry.Respelling != "" {
+			return entry.Respelling
+		}
+		return matched
+	}, func(s string) string { return s })
+}
+
+// แปลงข้อความเป็น SSML โดยใช้ <phoneme> กับคำที่มีสัทอักษร
+// ข้อความข้างใน <phoneme> คือคำอ่านสำรองสำหรับ engine ที่ไม่รองรับ SSML
+func (l *lexicon) applySSML(text string) string {
+	return l.replace(text, func(entry lexiconEntry, matched string) string {
+		fallback := matched
+		if entry.Respelling != "" {
+			fallback = entry.Respelling
+		}
+		if entry.Phoneme == "" {
+			return escapeSSML(fallback)
+		}
+		return fmt.Sprintf(`<phoneme alphabet="%s" ph="%s">%s</phoneme>`, entry.Alphabet, escapeSSML(entry.Phoneme), escapeSSML(fallback))
+	}, escapeSSML)
+}
+
+// ตรวจสอบว่าข้อความมีคำที่ต้องใช้ <phoneme>
+func (l *lexicon) needsSSML(text string) bool {
+	runes := []rune(text)
+	boundaries := defaultThaiDictionary().boundaries(runes)
+	for i := range runes {
+		if entry, ok := l.matchAt(runes, boundaries, i); ok && entry.Phoneme != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ตรวจสอบว่ามีคำใน lexicon ที่ตรงกับข้อความส่วนใดส่วนหนึ่ง
+func (l *lexicon) covers(text string) bool {
+	if l == nil {
+		return false
+	}
+	runes := []rune(text)
+	boundaries := defaultThaiDictionary().boundaries(runes)
+	for i := range runes {
+		if _, ok := l.matchAt(runes, boundaries, i); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hash ของเนื้อหา lexicon สำหรับ manifest
+func (l *lexicon) hash() string {
+	if l == nil {
+		return ""
+	}
+	words := make([]string, 0, len(l.entries))
+	for word := range l.entries {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	var sb strings.Builder
+	for _, word := range words {
+		e := l.entries[word]
+		sb.WriteString(strings.Join([]string{e.Word, e.Respelling, e.Alphabet, e.Phoneme}, "\x00") + "\n")
+	}
+	return hashString(sb.String())
+}
+
+// คำละตินที่ขึ้นต้นด้วยตัวพิมพ์ใหญ่ (อาจเป็นชื่อหลายคำติดกัน เช่น "Lin Feng")
+var properNounPattern = regexp.MustCompile(`\b[A-Z][a-z]+(?:[ -][A-Z][a-z]+)*\b`)
+
+// คำที่น่าจะเป็นชื่อเฉพาะในข้อความ: คำละตินตัวพิมพ์ใหญ่ และคำไทยที่ไม่มีในพจนานุกรม
+func properNounCandidates(text string) []string {
+	candidates := properNounPattern.FindAllString(text, -1)
+
+	dict := defaultThaiDictionary()
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isThaiRune(runes[i]) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && isThaiRune(runes[end]) {
+			end++
+		}
+		run := runes[i:end]
+		for _, s := range dict.segments(run) {
+			// ส่วนที่ไม่รู้จักสั้นๆ มักเป็นคำที่สะกดผิดหรือคำไม่มีในพจนานุกรม ไม่ใช่ชื่อ
+			if !s.known && s.end-s.start >= 3 {
+				candidates = append(candidates, string(run[s.start:s.end]))
+			}
+		}
+		i = end
+	}
+	return candidates
+}
+
+// คำสั่ง lexicon check: แสดงคำที่น่าจะเป็นชื่อเฉพาะแต่ยังไม่มีใน lexicon
+func runLexiconCommand(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("ใช้งาน: k-tts lexicon check [--lexicon lexicon.txt] [--min-count 2]")
+	}
+
+	cfg, err := loadConfig(nil)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("k-tts lexicon check", flag.ContinueOnError)
+	input := fs.String("input", cfg.InputDir, "folder ไฟล์ข้อความต้นฉบับ")
+	lexiconPath := fs.String("lexicon", cfg.Lexicon, "ไฟล์ lexicon")
+	minCount := fs.Int("min-count", 2, "แสดงเฉพาะคำที่พบอย่างน้อยกี่ครั้ง")
+	encoding := fs.String("encoding", cfg.Encoding, "การเข้ารหัสของไฟล์ข้อความ")
+	err = fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	files, err := findInputFiles(*input, cfg.Glob)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("ไม่พบไฟล์ %s ใน folder %s", cfg.Glob, *input)
+	}
+
+	counts := map[string]int{}
+	chapters := map[string]map[string]bool{}
+	lexicons := map[string]*lexicon{}
+	for _, file := range files {
+		inputs, err := readInputChapters(cfg, file, *encoding)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		for _, chapter := range inputs {
+			baseName := chapter.BaseName
+
+			// รวม lexicon เฉพาะบทด้วย คำที่แก้ไว้ในบทนั้นแล้วจึงไม่ถูกแสดง
+			chapterLexicon := cfg.Chapters[baseName].Lexicon
+			lex, ok := lexicons[chapterLexicon]
+			if !ok {
+				lex, err = loadLexicons(cfg.cleaner, *lexiconPath, chapterLexicon)
+				if err != nil {
+					return err
+				}
+				lexicons[chapterLexicon] = lex
+			}
+
+			text := stripEmphasis(chapter.Text)
+			if chapter.SSML {
+				text = ssmlToText(text)
+			} else {
+				text = cfg.cleaner.clean(text)
+			}
+			for _, word := range properNounCandidates(text) {
+				if lex.covers(word) {
+					continue
+				}
+				counts[word]++
+				if chapters[word] == nil {
+					chapters[word] = map[string]bool{}
+				}
+				chapters[word][baseName] = true
+			}
+		}
+	}
+
+	var words []string
+	for word, count := range counts {
+		if count >= *minCount {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	if len(words) == 0 {
+		fmt.Println("✅ ไม่พบคำที่น่าจะเป็นชื่อเฉพาะที่ยังไม่มีใน lexicon")
+		return nil
+	}
+	fmt.Printf("🔎 คำที่น่าจะเป็นชื่อเฉพาะแต่ยังไม่มีใน lexicon (%d คำ):\n", len(words))
+	fmt.Printf("%-24s %6s %s\n", "WORD", "COUNT", "CHAPTERS")
+	for _, word := range words {
+		var names []string
+		for name := range chapters[word] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 5 {
+			names = append(names[:5], "...")
+		}
+		fmt.Printf("%-24s %6d %s\n", word, counts[word], strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// lexicon จากเนื้อหาไฟล์ (ผ่าน loadLexicons เพื่อให้คำไทยถูกเพิ่มในพจนานุกรมตัดคำด้วย)
+func testLexicon(t *testing.T, content string) *lexicon {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "lexicon.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cleaner, err := newTextCleaner("none", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lex, err := loadLexicons(cleaner, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lex
+}
+
+func TestLexiconApplyLatinBoundaries(t *testing.T) {
+	lex := testLexicon(t, "Qi = ชี่ | ipa:tɕʰi\nmana = มานา\nLin Feng = หลินเฟิง\n")
+	tests := []struct {
+		in, want string
+	}{
+		{"Qi flows", "ชี่ flows"},
+		{"พลัง Qi,", "พลัง ชี่,"},
+		{"Qing", "Qing"}, // ไม่ตรงต้นคำอื่น
+		{"aQi", "aQi"},   // ไม่ตรงท้ายคำอื่น
+		{"Qi2", "Qi2"},   // ตัวเลขติดกันนับเป็นคำเดียว
+		{"QI", "QI"},     // คำที่มีตัวพิมพ์ใหญ่ต้องตรงทุกตัว
+		{"Mana", "มานา"}, // คำตัวพิมพ์เล็กล้วนตรงได้ทุกแบบ
+		{"MANA!", "มานา!"},
+		{"Qiมา", "ชี่มา"},        // อักษรไทยที่ติดกันไม่ใช่ตัวอักษรละติน
+		{"Lin Feng", "หลินเฟิง"}, // คำหลายคำ
+		{"Lin Fengs", "Lin Fengs"},
+	}
+	for _, tt := range tests {
+		if got := lex.apply(tt.in); got != tt.want {
+			t.Errorf("apply(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLexiconApplyThaiBoundaries(t *testing.T) {
+	lex := testLexicon(t, "ตา = ต้า\nหลินเฟิง = หลิน เฟิง\nเซียน = เซียน-น\n")
+	tests := []struct {
+		in, want string
+	}{
+		{"ตา ของเขา", "ต้า ของเขา"},
+		{"ตาของเขา", "ต้าของเขา"}, // ขอบเขตคำระหว่าง ตา กับ ของ
+		{"เขาตาย", "เขาตาย"},      // ไม่ตรงต้นคำ ตาย
+		{"ดวงตา", "ดวงตา"},        // ไม่ตรงท้ายคำ ดวงตา
+		{"ตามหา", "ตามหา"},        // ไม่ตรงต้นคำ ตาม
+		{"หลินเฟิงเดินออกไป", "หลิน เฟิงเดินออกไป"},
+		{"ศิษย์หลินเฟิงฮึดฮัด", "ศิษย์หลิน เฟิงฮึดฮัด"}, // ชื่อจาก lexicon เป็นคำหนึ่งคำแม้ติดกับคำที่ไม่รู้จัก
+		{"เซียนกระบี่", "เซียน-นกระบี่"},
+	}
+	for _, tt := range tests {
+		if got := lex.apply(tt.in); got != tt.want {
+			t.Errorf("apply(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if !lex.covers("หลินเฟิง") || lex.covers("ตาย") {
+		t.Error("covers does not follow Thai word boundaries")
+	}
+}
+
+func TestLexiconApplySSML(t *testing.T) {
+	lex := testLexicon(t, "Qi = ชี่ | ipa:tɕʰi\nXiao = x-sampa:s\\jaU\nmana = มานา\nตา = ต้า\n")
+	tests := []struct {
+		in        string
+		want      string
+		needsSSML bool
+	}{
+		{"Qi & ตา", `<phoneme alphabet="ipa" ph="tɕʰi">ชี่</phoneme> &amp; ต้า`, true},
+		// คำที่มีแต่สัทอักษรใช้คำเดิมเป็นคำอ่านสำรอง
+		{"Xiao <3", `<phoneme alphabet="x-sampa" ph="s\jaU">Xiao</phoneme> &lt;3`, true},
+		{"mana", "มานา", false},
+		{"เขาตาย", "เขาตาย", false},
+		{"Qing", "Qing", false},
+	}
+	for _, tt := range tests {
+		if got := lex.applySSML(tt.in); got != tt.want {
+			t.Errorf("applySSML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := lex.needsSSML(tt.in); got != tt.needsSSML {
+			t.Errorf("needsSSML(%q) = %v, want %v", tt.in, got, tt.needsSSML)
+		}
+	}
+
+	// apply คงคำที่มีแต่สัทอักษรไว้
+	if got := lex.apply("Xiao"); got != "Xiao" {
+		t.Errorf("apply(Xiao) = %q", got)
+	}
+}
+
+func TestLexiconLoadErrors(t *testing.T) {
+	cleaner, _ := newTextCleaner("none", nil)
+	for _, content := range []string{"ไม่มีเครื่องหมายเท่ากับ\n", "คำ =\n", " = คำอ่าน\n"} {
+		path := filepath.Join(t.TempDir(), "lexicon.txt")
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := loadLexicons(cleaner, path); err == nil {
+			t.Errorf("loadLexicons(%q) succeeded, want error", content)
+		}
+	}
+}
@@ -2,22 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
+	"io/fs"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
-
-	texttospeech "cloud.google.com/go/texttospeech/apiv1"
-	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"unicode/utf8"
 )
 
 // โครงสร้างข้อมูลสำหรับงานแต่ละไฟล์
@@ -26,6 +25,21 @@ type TTSJob struct {
 	FilePath   string
 	OutputPath string
 	Text       string
+	SSML       bool // ไฟล์ต้นฉบับเป็น SSML (.ssml)
+	Voice      VoiceSettings
+	Lexicon    *lexicon  // พจนานุกรมการออกเสียงของบท (nil = ไม่มี)
+	Title      string    // ชื่อบทจากสารบัญหรือบรรทัดแรก สำหรับ metadata (ว่าง = ไม่พบ)
+	Book       *bookInfo // หนังสือที่บทนี้อยู่ สำหรับ metadata (nil = ไม่มี)
+	Total      int       // จำนวนบททั้งหมดในรอบนี้ สำหรับหมายเลข track
+	// ข้อความประกาศชื่อบทก่อนเนื้อหา (ว่าง = ไม่ประกาศ)
+	Announcement string
+}
+
+// metadata ของหนังสือที่ใส่ในไฟล์เสียง output
+type audioMetadata struct {
+	Album  string
+	Artist string
+	Cover  string // ไฟล์รูปปก (ว่าง = ไม่มี)
 }
 
 // โครงสร้างข้อมูลสำหรับผลลัพธ์
@@ -34,12 +48,55 @@ type TTSResult struct {
 	Success bool
 	Error   error
 	Size    int64
+	Engine  string // engine ที่สร้างเสียงสำเร็จ
+	Missing []int  // หมายเลขส่วนที่ขาดหายเมื่อเปิด allow_partial (เริ่มที่ 1)
+	// ถูกยกเลิกด้วย SIGINT/SIGTERM ก่อนหรือระหว่างทำงาน
+	Canceled bool
+}
+
+// ฟังก์ชันวัดขนาดของตัวอักษรหนึ่งตัว
+type runeSizeFunc func(r rune) int
+
+// นับเป็นจำนวนตัวอักษร (สำหรับ Google Translate TTS)
+func runeCount(r rune) int {
+	return 1
+}
+
+// นับเป็นจำนวน byte แบบ UTF-8 หลัง escapeSSML (สำหรับ Google Cloud TTS ที่จำกัด 5000 bytes, ภาษาไทยใช้ 3 bytes ต่อตัว และ "&" เป็น "&amp;")
+func ssmlBytes(r rune) int {
+	switch r {
+	case '&':
+		return len("&amp;")
+	case '<', '>':
+		return len("&lt;")
+	case '"', '\'':
+		return len("&quot;")
+	}
+	return utf8.RuneLen(r)
+}
+
+// วัดขนาดข้อความด้วยฟังก์ชันที่กำหนด
+func measureText(text string, size runeSizeFunc) int {
+	total := 0
+	for _, r := range text {
+		total += size(r)
+	}
+	return total
 }
 
 // แบ่งข้อความเป็นส่วนย่อยสำหรับ Google Translate TTS
 func splitText(text string, maxLen int) []string {
-	runes := []rune(text)
-	if len(runes) <= maxLen {
+	return splitTextBy(text, maxLen, runeCount)
+}
+
+// แบ่งข้อความเป็นส่วนย่อยที่แต่ละส่วนไม่เกิน maxBytes เมื่อ escape เป็น SSML
+func splitTextSSML(text string, maxBytes int) []string {
+	return splitTextBy(text, maxBytes, ssmlBytes)
+}
+
+// แบ่งข้อความตามประโยคโดยวัดขนาดด้วยฟังก์ชันที่กำหนด
+func splitTextBy(text string, maxLen int, size runeSizeFunc) []string {
+	if measureText(text, size) <= maxLen {
 		return []string{text}
 	}
 
@@ -57,7 +114,7 @@ func splitText(text string, maxLen int) []string {
 		}
 		testPart += sentence
 
-		if len([]rune(testPart)) > maxLen && currentPart != "" {
+		if measureText(testPart, size) > maxLen && currentPart != "" {
 			parts = append(parts, strings.TrimSpace(currentPart))
 			currentPart = sentence
 		} else {
@@ -73,11 +130,11 @@ func splitText(text string, maxLen int) []string {
 	// หากยังมีส่วนที่ยาวเกินไป ให้แบ่งด้วยวิธีอัจฉริยะกว่า
 	var finalParts []string
 	for _, part := range parts {
-		if len([]rune(part)) <= maxLen {
+		if measureText(part, size) <= maxLen {
 			finalParts = append(finalParts, part)
 		} else {
 			// แบ่งด้วยการหาจุดแบ่งที่เหมาะสม
-			subParts := splitLongText(part, maxLen)
+			subParts := splitLongText(part, maxLen, size)
 			finalParts = append(finalParts, subParts...)
 		}
 	}
@@ -128,21 +185,25 @@ func isDigit(r rune) bool {
 }
 
 // แบ่งข้อความยาวด้วยการหาจุดแบ่งที่เหมาะสม
-func splitLongText(text string, maxLen int) []string {
+func splitLongText(text string, maxLen int, size runeSizeFunc) []string {
 	runes := []rune(text)
+	boundaries := defaultThaiDictionary().boundaries(runes)
 	var parts []string
 
 	start := 0
 	for start < len(runes) {
-		end := start + maxLen
-		if end > len(runes) {
-			end = len(runes)
+		// หาตำแหน่งไกลสุดที่ขนาดรวมยังไม่เกิน maxLen (อย่างน้อย 1 ตัวอักษร)
+		end := start
+		used := 0
+		for end < len(runes) && (end == start || used+size(runes[end]) <= maxLen) {
+			used += size(runes[end])
+			end++
 		}
 
 		// หาจุดแบ่งที่เหมาะสม
 		if end < len(runes) {
 			// หาช่องว่างย้อนกลับ
-			bestBreak := findBestBreakPoint(runes, start, end)
+			bestBreak := findBestBreakPoint(runes, boundaries, start, end)
 			if bestBreak > start {
 				end = bestBreak
 			}
@@ -161,10 +222,11 @@ func splitLongText(text string, maxLen int) []string {
 	return parts
 }
 
-// หาจุดแบ่งที่ดีที่สุด
-func findBestBreakPoint(runes []rune, start, maxEnd int) int {
+// หาจุดแบ่งที่ดีที่สุด (boundaries คือขอบเขตคำจากการตัดคำไทย)
+// แบ่งที่ maxEnd ได้ด้วยหากส่วนนี้จบพอดีที่ช่องว่างหรือขอบเขตคำ
+func findBestBreakPoint(runes []rune, boundaries []bool, start, maxEnd int) int {
 	// หาช่องว่างย้อนกลับจากจุดสิ้นสุด
-	for i := maxEnd - 1; i > start; i-- {
+	for i := min(maxEnd, len(runes)-1); i > start; i-- {
 		if runes[i] == ' ' {
 			return i
 		}
@@ -179,75 +241,71 @@ func findBestBreakPoint(runes []rune, start, maxEnd int) int {
 		}
 	}
 
-	// หาสระหรือพยัญชนะไทยที่เหมาะสม
+	// หาขอบเขตคำย้อนกลับ (ภาษาไทยไม่มีช่องว่างระหว่างคำ)
+	for i := maxEnd; i > start; i-- {
+		if boundaries[i] {
+			return i
+		}
+	}
+
+	// คำเดียวยาวเกิน: แบ่งโดยไม่แยกพยัญชนะออกจากสระหรือวรรณยุกต์
 	for i := maxEnd - 1; i > start; i-- {
-		r := runes[i]
-		// ตัวอักษรไทยที่เป็นจุดแบ่งที่ดี
-		if isThaiVowel(r) || isThaiToneMarker(r) {
-			// แบ่งหลังสระหรือวรรณยุกต์
-			if i+1 < maxEnd {
-				return i + 1
-			}
+		if isThaiClusterBoundary(runes, i) {
+			return i
 		}
 	}
 
 	return maxEnd
 }
 
-// ตรวจสอบสระไทย
-func isThaiVowel(r rune) bool {
-	return (r >= 0x0E30 && r <= 0x0E39) || // สระ
-		(r >= 0x0E40 && r <= 0x0E44) || // เ แ โ ใ ไ
-		r == 0x0E2D || r == 0x0E2E // อ ฮ
-}
-
-// ตรวจสอบวรรณยุกต์ไทย
-func isThaiToneMarker(r rune) bool {
-	return r >= 0x0E48 && r <= 0x0E4B // ่ ้ ๊ ๋
-}
-
-// ทำความสะอาดข้อความโดยลบอักขระพิเศษที่ไม่ต้องการให้อ่าน
-func cleanTextForTTS(text string) string {
-	// ลบอักขระพิเศษที่ไม่ต้องการ
-	specialChars := []string{
-		"#", "*", "_", "~", "`", "^", "|", "\\", "/",
-		"[", "]", "{", "}", "<", ">", "@", "$", "%",
-		"&", "+", "=", "§", "¶", "†", "‡", "•", "…",
+// ตรวจสอบและสร้าง folder
+func ensureDir(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return os.MkdirAll(path, 0755)
 	}
+	return nil
+}
 
-	cleaned := text
-	for _, char := range specialChars {
-		cleaned = strings.ReplaceAll(cleaned, char, " ")
+// หาไฟล์ใน folder ตามรูปแบบชื่อไฟล์ (หลายรูปแบบคั่นด้วย ,) โดยไม่ซ้ำกัน
+// dir ที่เป็นไฟล์ (เช่น book.epub) คืนไฟล์นั้นไฟล์เดียว
+func findInputFiles(dir, globs string) ([]string, error) {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return []string{dir}, nil
 	}
-
-	// ลบเลขบท/หมายเลขที่อยู่ในบรรทัดเดี่ยว (เช่น "1" "2" "บทที่ 1")
-	lines := strings.Split(cleaned, "\n")
-	var filteredLines []string
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// ข้ามบรรทัดที่มีแต่ตัวเลข หรือ "บทที่ X" หรือ "Chapter X"
-		if trimmed == "" ||
-			regexp.MustCompile(`^(\d+|บทที่\s*\d+|Chapter\s*\d+)$`).MatchString(trimmed) {
+	var files []string
+	seen := map[string]bool{}
+	for _, glob := range strings.Split(globs, ",") {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
 			continue
 		}
-		filteredLines = append(filteredLines, line)
+		matches, err := filepath.Glob(filepath.Join(dir, glob))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
 	}
+	return files, nil
+}
 
-	cleaned = strings.Join(filteredLines, "\n")
-
-	// ลบช่องว่างที่ซ้ำซ้อน
-	cleaned = regexp.MustCompile(`\s+`).ReplaceAllString(cleaned, " ")
-
-	return strings.TrimSpace(cleaned)
+// ไฟล์ชั่วคราวระหว่างประมวลผลไฟล์ output (นามสกุลเดียวกันเพื่อให้ ffmpeg เลือก format ถูก)
+func tempOutputPath(outputPath string) string {
+	return outputPath + ".temp" + filepath.Ext(outputPath)
 }
 
-// ตรวจสอบและสร้าง folder
-func ensureDir(path string) error {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return os.MkdirAll(path, 0755)
+// ลบไฟล์ output และไฟล์ชั่วคราวของงานที่ทำไม่เสร็จ
+func removePartialOutputs(outputPath string) {
+	for _, path := range []string{outputPath, tempOutputPath(outputPath)} {
+		os.Remove(path)
+	}
+	for _, format := range supportedSubtitleFormats {
+		os.Remove(subtitlePath(outputPath, format))
 	}
-	return nil
 }
 
 // ลบไฟล์ใน folder temp
@@ -263,8 +321,8 @@ func cleanTempFolder(tempDir string) {
 
 // ฟังก์ชันสำหรับเรียงลำดับไฟล์ตามหมายเลขที่ฝังในชื่อไฟล์แบบธรรมชาติ (Natural Sorting)
 func sortFilesNaturally(files []string) {
-	// ใช้ regex เพื่อดึงหมายเลขจากชื่อไฟล์ temp_part_*.mp3
-	re := regexp.MustCompile(`temp_part_(\d+)\.mp3`)
+	// ใช้ regex เพื่อดึงหมายเลขจากชื่อไฟล์ temp_part_*.mp3 (หรือนามสกุลอื่นตาม engine)
+	re := regexp.MustCompile(`temp_part_(\d+)\.`)
 
 	sort.Slice(files, func(i, j int) bool {
 		// ดึงหมายเลขจากไฟล์ i
@@ -285,360 +343,262 @@ func sortFilesNaturally(files []string) {
 	})
 }
 
-// รวมไฟล์เสียงด้วย ffmpeg
-func combineAudioFiles(tempDir, outputFile string) error {
-	// หาไฟล์ temp_part_*.mp3 ใน tempDir
-	pattern := filepath.Join(tempDir, "temp_part_*.mp3")
-	files, err := filepath.Glob(pattern)
-	if err != nil || len(files) == 0 {
-		return fmt.Errorf("ไม่พบไฟล์เสียงใน %s", tempDir)
-	}
-
-	// เรียงลำดับไฟล์แบบ natural sorting (1, 2, 3, ..., 10, 11, 12 แทนที่จะเป็น 1, 10, 11, 12, 2, 3)
-	sortFilesNaturally(files)
-
-	// แสดงลำดับไฟล์ที่จะรวม
-	fmt.Println("📋 ลำดับไฟล์ที่จะรวม:")
-	for i, file := range files {
-		fmt.Printf("   %d. %s\n", i+1, filepath.Base(file))
-	}
-
-	if len(files) == 1 {
-		// หากมีไฟล์เดียว ให้คัดลอกไปยัง output
-		data, err := os.ReadFile(files[0])
+// เตรียมข้อความของงาน: ทำความสะอาดข้อความธรรมดา หรือตรวจสอบเอกสาร SSML
+func prepareJobText(cfg *Config, job TTSJob) (PreparedText, error) {
+	if job.SSML {
+		doc, err := normalizeSSML(job.Text)
 		if err != nil {
-			return err
+			return PreparedText{}, fmt.Errorf("SSML ไม่ถูกต้อง: %v", err)
+		}
+		if ssmlToText(doc) == "" {
+			return PreparedText{}, fmt.Errorf("ไม่มีข้อความที่สามารถอ่านได้ใน SSML")
 		}
-		return os.WriteFile(outputFile, data, 0644)
+		return PreparedText{Text: doc, SSML: true}, nil
 	}
 
-	// สร้างไฟล์รายการสำหรับ ffmpeg (ใช้ relative path จาก tempDir)
-	filelistPath := filepath.Join(tempDir, "filelist.txt")
-	var filelistContent strings.Builder
-	for _, file := range files {
-		// ใช้ชื่อไฟล์เท่านั้น (relative path)
-		filename := filepath.Base(file)
-		filelistContent.WriteString(fmt.Sprintf("file '%s'\n", filename))
+	// ทำความสะอาดข้อความก่อนประมวลผล
+	cleanedText := cfg.cleaner.clean(job.Text)
+	if cleanedText == "" {
+		return PreparedText{}, fmt.Errorf("ไม่มีข้อความที่สามารถอ่านได้หลังจากทำความสะอาด")
 	}
+	return prepareCleanedText(cfg, job.Lexicon, cleanedText), nil
+}
 
-	err = os.WriteFile(filelistPath, []byte(filelistContent.String()), 0644)
-	if err != nil {
-		return err
+// แปลงข้อความที่ทำความสะอาดแล้วเป็นข้อความที่ส่งให้ engine
+func prepareCleanedText(cfg *Config, lex *lexicon, cleanedText string) PreparedText {
+	// แทนที่คำตาม lexicon (คำที่มีสัทอักษรต้องใช้ SSML <phoneme>)
+	// ส่วนที่เน้นจาก Markdown/HTML ต้องใช้ SSML <emphasis> เช่นกัน
+	needsSSML := hasEmphasis(cleanedText)
+	render := escapeSSML
+	if lex != nil {
+		needsSSML = needsSSML || lex.needsSSML(cleanedText)
+		render = lex.applySSML
 	}
-
-	// รันคำสั่ง ffmpeg จาก tempDir โดยใช้ absolute path สำหรับ output
-	absOutputFile, err := filepath.Abs(outputFile)
-	if err != nil {
-		return err
-	}
-
-	// ใช้ high-quality encoding parameters
-	cmd := exec.Command("ffmpeg",
-		"-f", "concat",
-		"-safe", "0",
-		"-i", "filelist.txt",
-		"-c:a", "libmp3lame", // ใช้ LAME MP3 encoder คุณภาพสูง
-		"-b:a", "320k", // Bitrate 320kbps (คุณภาพสูงสุด)
-		"-ar", "48000", // Sample rate 48kHz
-		"-ac", "2", // Stereo
-		"-af", "volume=1.2,dynaudnorm=p=0.9:s=5", // ปรับ volume และ normalize
-		absOutputFile,
-		"-y")
-
-	cmd.Dir = tempDir
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg error: %v\nOutput: %s", err, string(output))
+	if needsSSML {
+		return PreparedText{Text: textToSSMLWith(cleanedText, cfg.documentPauses(), emphasisRenderer(render)), SSML: true}
 	}
-
-	// ลบไฟล์รายการ
-	os.Remove(filelistPath)
-
-	return nil
+	if lex != nil {
+		cleanedText = lex.apply(cleanedText)
+	}
+	return PreparedText{Text: cleanedText}
 }
 
-// ปรับความเร็วของไฟล์เสียงด้วย ffmpeg
-func adjustAudioSpeed(inputFile, outputFile string, speed float64) error {
-	// สร้างไฟล์ temp สำหรับการปรับความเร็ว
-	tempFile := inputFile + ".temp.mp3"
-
-	fmt.Printf("⚡ กำลังปรับความเร็วเป็น %.1fx...\n", speed)
-
-	// สร้าง atempo filter สำหรับความเร็วสูง (แบ่งเป็นขั้นๆ หากเกิน 2.0)
-	var audioFilter string
-	if speed <= 2.0 {
-		audioFilter = fmt.Sprintf("atempo=%.2f,dynaudnorm=p=0.9:s=5", speed)
-	} else {
-		// สำหรับความเร็วสูงกว่า 2.0 ต้องใช้ atempo หลายครั้ง
-		// เช่น 2.4x = 1.5 * 1.6
-		firstStep := 1.5
-		secondStep := speed / firstStep
-		if secondStep > 2.0 {
-			// หากยังเกิน 2.0 ให้แบ่งเป็น 3 ขั้น
-			firstStep = 1.4
-			secondStep = 1.5
-			thirdStep := speed / (firstStep * secondStep)
-			audioFilter = fmt.Sprintf("atempo=%.2f,atempo=%.2f,atempo=%.2f,dynaudnorm=p=0.9:s=5", firstStep, secondStep, thirdStep)
-		} else {
-			audioFilter = fmt.Sprintf("atempo=%.2f,atempo=%.2f,dynaudnorm=p=0.9:s=5", firstStep, secondStep)
+// สร้างเสียงของส่วนหนึ่ง โดยใช้เสียงจาก cache หากเคยสร้างไว้แล้ว (cache เป็น nil ได้)
+func synthesizeChunk(ctx context.Context, cache *chunkCache, engine Synthesizer, req SynthesisRequest) (*AudioChunk, error) {
+	if req.Pause > 0 {
+		generator, ok := engine.(silenceGenerator)
+		if !ok {
+			return nil, fmt.Errorf("%s ไม่รองรับการสร้างช่วงเงียบ", engine.Name())
 		}
+		return generator.Silence(ctx, req.Pause)
+	}
+	if cache == nil {
+		return engine.Synthesize(ctx, req)
 	}
 
-	// ใช้ high-quality speed adjustment
-	cmd := exec.Command("ffmpeg",
-		"-i", inputFile,
-		"-af", audioFilter, // รวม atempo และ dynaudnorm ใน filter เดียว
-		"-c:a", "libmp3lame", // High-quality MP3 encoder
-		"-b:a", "320k", // Maximum bitrate
-		"-ar", "48000", // High sample rate
-		"-ac", "2", // Stereo
-		tempFile,
-		"-y")
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// ลบไฟล์ temp หากมีข้อผิดพลาด
-		os.Remove(tempFile)
-		return fmt.Errorf("ffmpeg speed adjustment error: %v\nOutput: %s", err, string(output))
+	key := chunkCacheKey(engine, req)
+	if chunk, ok := cache.Get(key); ok {
+		fmt.Printf("♻️ Worker: ใช้เสียงจาก cache (%s)\n", key[:12])
+		return chunk, nil
 	}
 
-	// แทนที่ไฟล์เดิมด้วยไฟล์ที่ปรับความเร็วแล้ว
-	err = os.Rename(tempFile, outputFile)
+	chunk, err := engine.Synthesize(ctx, req)
 	if err != nil {
-		os.Remove(tempFile)
-		return fmt.Errorf("ไม่สามารถแทนที่ไฟล์ได้: %v", err)
+		return nil, err
 	}
-
-	return nil
-}
-
-// เพิ่มฟังก์ชันสำหรับ post-processing เสียงคุณภาพสูง
-func enhanceAudioQuality(inputFile, outputFile string) error {
-	fmt.Println("🎛️ กำลังปรับปรุงคุณภาพเสียง...")
-
-	cmd := exec.Command("ffmpeg",
-		"-i", inputFile,
-		"-c:a", "libmp3lame",
-		"-b:a", "320k",
-		"-ar", "48000",
-		"-ac", "2",
-		"-af", "highpass=f=80,lowpass=f=15000,dynaudnorm=p=0.9:s=5:m=15,volume=1.1", // Filter chain สำหรับปรับปรุงเสียง
-		outputFile,
-		"-y")
-
-	output, err := cmd.CombinedOutput()
+	err = cache.Put(key, chunk)
 	if err != nil {
-		return fmt.Errorf("ffmpeg enhancement error: %v\nOutput: %s", err, string(output))
+		fmt.Printf("⚠️ Worker: ไม่สามารถบันทึก cache: %s\n", err.Error())
 	}
-
-	return nil
+	return chunk, nil
 }
 
-// ประมวลผลไฟล์เดียวด้วย Google Translate TTS สำหรับ multi-worker
-func processFileWithGoogleTTSWorker(job TTSJob, workerTempDir string) ([]string, error) {
-	fmt.Printf("🔄 Worker กำลังประมวลผล: %s\n", filepath.Base(job.FilePath))
+// สร้างเสียงของงานหนึ่งด้วย engine ที่กำหนด แล้วรวมเป็นไฟล์ output
+// ส่วนที่ล้มเหลวหลังลองใหม่ครบทำให้ทั้งบทล้มเหลว เว้นแต่เปิด allow_partial จึงคืนหมายเลขส่วนที่ขาด
+// เมื่อเปิด subtitles จะคืนข้อความและความยาวเสียงของแต่ละส่วนด้วย (nil = วัดความยาวไม่ได้)
+func synthesizeJob(ctx context.Context, cfg *Config, cache *chunkCache, engine Synthesizer, job TTSJob, workerTempDir string) ([]int, []spokenSegment, error) {
+	fmt.Printf("🔄 Worker กำลังประมวลผล: %s ด้วย %s\n", filepath.Base(job.FilePath), engine.Name())
 
-	// ทำความสะอาดข้อความก่อนประมวลผล
-	cleanedText := cleanTextForTTS(job.Text)
-	if cleanedText == "" {
-		return nil, fmt.Errorf("ไม่มีข้อความที่สามารถอ่านได้หลังจากทำความสะอาด")
+	prepared, err := prepareJobText(cfg, job)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// แบ่งข้อความเป็นส่วนย่อย (เพิ่มขนาดให้เหมาะสมกับภาษาไทย)
-	parts := splitText(cleanedText, 150)
-	fmt.Printf("📑 ไฟล์ %s แบ่งเป็น %d ส่วน\n", filepath.Base(job.FilePath), len(parts))
-
-	var audioFiles []string
-
-	for i, part := range parts {
-		fmt.Printf("🎵 Worker กำลังสร้างเสียง %s ส่วน %d/%d...\n", filepath.Base(job.FilePath), i+1, len(parts))
-
-		// เข้ารหัส URL
-		encodedText := url.QueryEscape(part)
-		ttsURL := fmt.Sprintf("https://translate.google.com/translate_tts?ie=UTF-8&tl=th&client=tw-ob&q=%s", encodedText)
-
-		// สร้าง HTTP request พร้อม headers
-		req, err := http.NewRequest("GET", ttsURL, nil)
-		if err != nil {
-			fmt.Printf("⚠️ Worker: ไม่สามารถสร้าง request %s ส่วน %d: %s\n", filepath.Base(job.FilePath), i+1, err.Error())
-			continue
-		}
-
-		// เพิ่ม headers ที่จำเป็น
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-		req.Header.Set("Referer", "https://translate.google.com/")
-
-		// ส่ง request
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Printf("⚠️ Worker: ไม่สามารถดาวน์โหลดเสียง %s ส่วน %d: %s\n", filepath.Base(job.FilePath), i+1, err.Error())
-			continue
-		}
-
-		// ตรวจสอบ status code
-		if resp.StatusCode != 200 {
-			fmt.Printf("⚠️ Worker: ได้รับ status code %d สำหรับ %s ส่วน %d\n", resp.StatusCode, filepath.Base(job.FilePath), i+1)
-			resp.Body.Close()
-			continue
-		}
+	reqs, err := engine.Chunk(prepared)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range reqs {
+		reqs[i].Voice = job.Voice
+	}
 
-		// อ่านข้อมูลเสียง
-		audioData, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+	// ประกาศชื่อบทก่อนเนื้อหา
+	if job.Announcement != "" {
+		titleReqs, err := titleRequests(cfg, engine, job)
 		if err != nil {
-			fmt.Printf("⚠️ Worker: ไม่สามารถอ่านข้อมูลเสียง %s ส่วน %d: %s\n", filepath.Base(job.FilePath), i+1, err.Error())
-			continue
-		}
-
-		// ตรวจสอบว่าได้ไฟล์เสียงจริงๆ
-		if len(audioData) < 1000 || strings.Contains(string(audioData[:100]), "<html") {
-			fmt.Printf("⚠️ Worker: ได้รับข้อมูลที่ไม่ใช่เสียงสำหรับ %s ส่วน %d\n", filepath.Base(job.FilePath), i+1)
-			continue
+			return nil, nil, fmt.Errorf("ไม่สามารถเตรียมชื่อบท: %v", err)
 		}
+		reqs = append(titleReqs, reqs...)
+	}
 
-		// บันทึกไฟล์ส่วนย่อยใน workerTempDir
-		tempFilename := filepath.Join(workerTempDir, fmt.Sprintf("temp_part_%d.mp3", i+1))
-		err = os.WriteFile(tempFilename, audioData, 0644)
+	// อ่านข้อความภาษาอื่นด้วยเสียงหรือภาษาของตัวเอง
+	if cfg.MixedLanguage {
+		reqs, err = splitRequestsByLanguage(cfg, reqs)
 		if err != nil {
-			fmt.Printf("⚠️ Worker: ไม่สามารถบันทึกไฟล์ %s ส่วน %d: %s\n", filepath.Base(job.FilePath), i+1, err.Error())
-			continue
+			return nil, nil, err
 		}
+	}
+	fmt.Printf("📑 ไฟล์ %s แบ่งเป็น %d ส่วน\n", filepath.Base(job.FilePath), len(reqs))
 
-		audioFiles = append(audioFiles, tempFilename)
-		fmt.Printf("✅ Worker: บันทึก %s ส่วน %d สำเร็จ (%.1f KB)\n", filepath.Base(job.FilePath), i+1, float64(len(audioData))/1024)
+	missing, segments, err := synthesizeRequests(ctx, cfg, cache, engine, job, reqs, workerTempDir)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		// รอระหว่างการดาวน์โหลดเพื่อไม่ให้ถูก rate limit
-		time.Sleep(800 * time.Millisecond)
+	// รวมไฟล์ ปรับความเร็ว EQ และ normalize แล้วเข้ารหัสครั้งเดียว
+	// รูปแบบอื่นนอกจาก MP3 ใส่ tag ด้วย ffmpeg ในขั้นนี้ (MP3 ใช้ ID3 writer หลังเข้ารหัส)
+	profile := cfg.outputProfile()
+	var metadataArgs []string
+	if !profile.usesID3() {
+		metadataArgs = cfg.chapterTag(job, engine.Name()).ffmpegArgs()
+	}
+	fmt.Printf("🔗 Worker: กำลังรวมไฟล์เสียง %s...\n", filepath.Base(job.FilePath))
+	err = renderChapterAudio(ctx, workerTempDir, job.OutputPath, cfg.audioPipeline(engine), profile, metadataArgs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ไม่สามารถรวมไฟล์เสียงได้: %v", err)
 	}
 
-	return audioFiles, nil
+	return missing, segments, nil
 }
 
-// ประมวลผลไฟล์เดียวด้วย Google Cloud TTS สำหรับ multi-worker
-func processFileWithCloudTTSWorker(client *texttospeech.Client, ctx context.Context, job TTSJob) error {
-	fmt.Printf("🔄 Worker กำลังประมวลผล: %s ด้วย Google Cloud TTS\n", filepath.Base(job.FilePath))
+// สร้างเสียงทีละส่วนแล้วบันทึกเป็น temp_part_N ใน workerTempDir
+// ส่วนที่ล้มเหลวทำให้ทั้งบทล้มเหลว เว้นแต่เปิด allow_partial จึงคืนหมายเลขส่วนที่ขาด (เริ่มที่ 1)
+func synthesizeRequests(ctx context.Context, cfg *Config, cache *chunkCache, engine Synthesizer, job TTSJob, reqs []SynthesisRequest, workerTempDir string) ([]int, []spokenSegment, error) {
+	var audioFiles []string
+	var missing []int
+	var segments []spokenSegment
+	timing := len(cfg.subtitleFormats()) > 0
+	for i, req := range reqs {
+		fmt.Printf("🎵 Worker กำลังสร้างเสียง %s ส่วน %d/%d...\n", filepath.Base(job.FilePath), i+1, len(reqs))
+
+		chunk, err := synthesizeChunk(ctx, cache, engine, req)
+		if err == nil {
+			// บันทึกไฟล์ส่วนย่อยใน workerTempDir
+			tempFilename := filepath.Join(workerTempDir, fmt.Sprintf("temp_part_%d.%s", i+1, chunk.Format))
+			err = os.WriteFile(tempFilename, chunk.Data, 0644)
+			if err == nil {
+				audioFiles = append(audioFiles, tempFilename)
+				fmt.Printf("✅ Worker: บันทึก %s ส่วน %d สำเร็จ (%.1f KB)\n", filepath.Base(job.FilePath), i+1, float64(len(chunk.Data))/1024)
+
+				// วัดความยาวเสียงของส่วนนี้สำหรับคำบรรยาย
+				if timing {
+					duration, err := probeDuration(tempFilename)
+					if err != nil {
+						fmt.Printf("⚠️ Worker: วัดความยาวเสียง %s ส่วน %d ไม่ได้ จะไม่เขียนคำบรรยาย: %s\n", filepath.Base(job.FilePath), i+1, err.Error())
+						timing = false
+						segments = nil
+					} else {
+						segments = append(segments, requestSegment(req, time.Duration(duration*float64(time.Second))))
+					}
+				}
+				continue
+			}
+		}
 
-	// ทำความสะอาดข้อความก่อนประมวลผล
-	cleanedText := cleanTextForTTS(job.Text)
-	if cleanedText == "" {
-		return fmt.Errorf("ไม่มีข้อความที่สามารถอ่านได้หลังจากทำความสะอาด")
-	}
-
-	// สร้าง request
-	req := &texttospeechpb.SynthesizeSpeechRequest{
-		Input: &texttospeechpb.SynthesisInput{
-			InputSource: &texttospeechpb.SynthesisInput_Text{Text: cleanedText},
-		},
-		Voice: &texttospeechpb.VoiceSelectionParams{
-			LanguageCode: "th-TH",
-			Name:         "th-TH-Neural2-C",
-			SsmlGender:   texttospeechpb.SsmlVoiceGender_FEMALE,
-		},
-		AudioConfig: &texttospeechpb.AudioConfig{
-			AudioEncoding:   texttospeechpb.AudioEncoding_MP3,
-			SampleRateHertz: 48000,
-			SpeakingRate:    1.0,
-			Pitch:           0.0,
-			VolumeGainDb:    2.0,
-		},
-	}
-
-	// เรียก API
-	fmt.Printf("🎙️ Worker กำลังสร้างเสียง %s ด้วย Google Cloud TTS...\n", filepath.Base(job.FilePath))
-	resp, err := client.SynthesizeSpeech(ctx, req)
-	if err != nil {
-		return fmt.Errorf("ไม่สามารถสร้างเสียงได้: %v", err)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if !cfg.AllowPartial {
+			return nil, nil, fmt.Errorf("ส่วน %d/%d: %v", i+1, len(reqs), err)
+		}
+		fmt.Printf("⚠️ Worker: %s ส่วน %d: %s\n", filepath.Base(job.FilePath), i+1, err.Error())
+		missing = append(missing, i+1)
 	}
 
-	// บันทึกไฟล์ชั่วคราว
-	tempFile := job.OutputPath + ".temp.mp3"
-	err = os.WriteFile(tempFile, resp.AudioContent, 0644)
-	if err != nil {
-		return fmt.Errorf("ไม่สามารถบันทึกไฟล์เสียงชั่วคราวได้: %v", err)
+	if len(audioFiles) == 0 {
+		return nil, nil, fmt.Errorf("ไม่สามารถสร้างเสียงได้แม้แต่ส่วนเดียว")
 	}
+	return missing, segments, nil
+}
 
-	// ปรับปรุงคุณภาพเสียง
-	err = enhanceAudioQuality(tempFile, job.OutputPath)
-	if err != nil {
-		os.Remove(tempFile)
-		return fmt.Errorf("ไม่สามารถปรับปรุงคุณภาพเสียงได้: %v", err)
+// แสดงหมายเลขส่วน เช่น "3, 7, 12"
+func formatChunkList(indices []int) string {
+	parts := make([]string, len(indices))
+	for i, n := range indices {
+		parts[i] = strconv.Itoa(n)
 	}
-
-	// ลบไฟล์ชั่วคราว
-	os.Remove(tempFile)
-	return nil
+	return strings.Join(parts, ", ")
 }
 
 // TTS Worker function
-func ttsWorker(workerID int, jobs <-chan TTSJob, results chan<- TTSResult, client *texttospeech.Client, ctx context.Context, useCloudTTS bool, audioSpeed float64) {
+func ttsWorker(ctx context.Context, cfg *Config, cache *chunkCache, workerID int, jobs <-chan TTSJob, results chan<- TTSResult, chain []Synthesizer) {
 	fmt.Printf("🚀 Worker %d เริ่มทำงาน\n", workerID)
 
 	for job := range jobs {
+		// ไม่เริ่มงานใหม่หลังถูกยกเลิก
+		if ctx.Err() != nil {
+			results <- TTSResult{Job: job, Error: fmt.Errorf("ถูกยกเลิก"), Canceled: true}
+			continue
+		}
+
 		fmt.Printf("👷 Worker %d รับงาน: %s\n", workerID, filepath.Base(job.FilePath))
 
 		// สร้าง temp directory สำหรับ worker นี้
-		workerTempDir := filepath.Join("output", fmt.Sprintf("temp_worker_%d", workerID))
+		workerTempDir := filepath.Join(cfg.OutputDir, fmt.Sprintf("temp_worker_%d", workerID))
 		err := ensureDir(workerTempDir)
 		if err != nil {
 			results <- TTSResult{Job: job, Success: false, Error: fmt.Errorf("ไม่สามารถสร้าง temp directory: %v", err)}
 			continue
 		}
 
-		var processingError error
-
-		if useCloudTTS && client != nil {
-			// ใช้ Google Cloud TTS
-			err = processFileWithCloudTTSWorker(client, ctx, job)
-			if err != nil {
-				fmt.Printf("❌ Worker %d: Google Cloud TTS ล้มเหลว: %s\n", workerID, err.Error())
-				// fallback ไป Google Translate TTS
-				audioFiles, err2 := processFileWithGoogleTTSWorker(job, workerTempDir)
-				if err2 != nil || len(audioFiles) == 0 {
-					processingError = fmt.Errorf("cloud TTS และ Translate TTS ล้มเหลวทั้งคู่: %v, %v", err, err2)
-				} else {
-					// รวมไฟล์เสียง
-					err = combineAudioFiles(workerTempDir, job.OutputPath)
-					if err != nil {
-						processingError = fmt.Errorf("ไม่สามารถรวมไฟล์เสียงได้: %v", err)
-					}
-				}
+		// ลองทีละ engine ตาม fallback chain
+		var engineErrors []string
+		var usedEngine string
+		var missing []int
+		var segments []spokenSegment
+		processingError := fmt.Errorf("ไม่มี engine ที่ใช้งานได้")
+		for _, engine := range chain {
+			cleanTempFolder(workerTempDir)
+			missing, segments, err = synthesizeJob(ctx, cfg, cache, engine, job, workerTempDir)
+			if err == nil {
+				processingError = nil
+				usedEngine = engine.Name()
+				break
 			}
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Printf("❌ Worker %d: %s ล้มเหลว: %s\n", workerID, engine.Name(), err.Error())
+			engineErrors = append(engineErrors, fmt.Sprintf("%s: %v", engine.Name(), err))
+			processingError = fmt.Errorf("ทุก engine ล้มเหลว: %s", strings.Join(engineErrors, "; "))
+		}
 
-			// ปรับความเร็วสำหรับ Cloud TTS
-			if processingError == nil {
-				tempSpeedFile := job.OutputPath + ".speed.mp3"
-				err = adjustAudioSpeed(job.OutputPath, tempSpeedFile, audioSpeed)
-				if err == nil {
-					os.Rename(tempSpeedFile, job.OutputPath)
-					fmt.Printf("⚡ Worker %d: ปรับความเร็วเสร็จสิ้น (%.1fx)\n", workerID, audioSpeed)
-				} else {
-					fmt.Printf("⚠️ Worker %d: ไม่สามารถปรับความเร็วได้: %s\n", workerID, err.Error())
+		if processingError == nil {
+			// ใส่ tag หลัง ffmpeg ทำงานเสร็จ
+			tag := cfg.chapterTag(job, usedEngine)
+			if cfg.outputProfile().usesID3() {
+				err = writeID3Tag(job.OutputPath, tag)
+				if err != nil {
+					fmt.Printf("⚠️ Worker %d: ไม่สามารถเขียน ID3 tag: %s\n", workerID, err.Error())
 				}
 			}
-		} else {
-			// ใช้ Google Translate TTS
-			audioFiles, err := processFileWithGoogleTTSWorker(job, workerTempDir)
-			if err != nil || len(audioFiles) == 0 {
-				processingError = fmt.Errorf("google Translate TTS ล้มเหลว: %v", err)
-			} else {
-				// รวมไฟล์เสียง
-				fmt.Printf("🔗 Worker %d: กำลังรวมไฟล์เสียง %s...\n", workerID, filepath.Base(job.FilePath))
-				err = combineAudioFiles(workerTempDir, job.OutputPath)
+
+			// คำบรรยายตามเวลาของไฟล์เสียงสุดท้าย (หลังปรับความเร็ว)
+			if segments != nil {
+				err = writeSubtitles(cfg, job, segmentCues(segments, captionUnits(cfg, job), cfg.Speed), tag)
 				if err != nil {
-					processingError = fmt.Errorf("ไม่สามารถรวมไฟล์เสียงได้: %v", err)
-				} else {
-					// ปรับความเร็วไฟล์เสียง
-					err = adjustAudioSpeed(job.OutputPath, job.OutputPath, audioSpeed)
-					if err != nil {
-						fmt.Printf("⚠️ Worker %d: ไม่สามารถปรับความเร็วได้: %s\n", workerID, err.Error())
-					} else {
-						fmt.Printf("⚡ Worker %d: ปรับความเร็วเสร็จสิ้น (%.1fx)\n", workerID, audioSpeed)
-					}
+					fmt.Printf("⚠️ Worker %d: ไม่สามารถเขียนคำบรรยาย: %s\n", workerID, err.Error())
 				}
 			}
 		}
 
+		// ถูกยกเลิกระหว่างทำงาน: ลบไฟล์ output ที่อาจเขียนไม่เสร็จ
+		canceled := ctx.Err() != nil
+		if canceled {
+			processingError = fmt.Errorf("ถูกยกเลิก")
+			removePartialOutputs(job.OutputPath)
+		}
+
 		// ลบไฟล์ temp ของ worker นี้
 		cleanTempFolder(workerTempDir)
 		os.Remove(workerTempDir)
@@ -655,37 +615,104 @@ func ttsWorker(workerID int, jobs <-chan TTSJob, results chan<- TTSResult, clien
 		}
 
 		results <- TTSResult{
-			Job:     job,
-			Success: processingError == nil,
-			Error:   processingError,
-			Size:    fileSize,
+			Job:      job,
+			Success:  processingError == nil,
+			Error:    processingError,
+			Size:     fileSize,
+			Engine:   usedEngine,
+			Missing:  missing,
+			Canceled: canceled,
 		}
 	}
 
 	fmt.Printf("🏁 Worker %d เสร็จสิ้นงาน\n", workerID)
 }
 
+// exit code เมื่อถูกยกเลิกด้วย SIGINT/SIGTERM (ตามธรรมเนียม 128 + SIGINT)
+const exitInterrupted = 130
+
+// คำสั่งย่อย เช่น "k-tts voices"
+var commands = map[string]func(args []string) error{
+	"voices":  runVoicesCommand,
+	"cache":   runCacheCommand,
+	"lexicon": runLexiconCommand,
+	"clean":   runCleanCommand,
+}
+
 func main() {
-	// ตั้งค่าความเร็ว (1.0 = ปกติ, 1.3 = เร็วขึ้น 30%, 1.4 = เร็วขึ้น 40%)
-	const AUDIO_SPEED_MULTIPLIER = 1.6
-	// จำนวน workers (จำนวนไฟล์ที่ประมวลผลพร้อมกัน)
-	const NUM_WORKERS = 4
+	// คำสั่งย่อย
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			err := command(os.Args[2:])
+			if err == flag.ErrHelp {
+				return
+			}
+			if err != nil {
+				fmt.Printf("❌ %s\n", err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
 
-	fmt.Printf("🚀 เริ่มต้นระบบ Multi-Worker TTS (%d workers)\n", NUM_WORKERS)
+	// โหลดการตั้งค่า (ไฟล์ config, environment variables และ flags)
+	cfg, err := loadConfig(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ %s\n", err.Error())
+		os.Exit(2)
+	}
+
+	// ยกเลิกงานเมื่อได้รับ SIGINT/SIGTERM (กดครั้งที่สองเพื่อบังคับปิดทันที)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	code := runBatch(ctx, cfg)
+	stop()
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// ประมวลผลทุกบทและคืนค่า exit code
+func runBatch(ctx context.Context, cfg *Config) int {
+	fmt.Printf("🚀 เริ่มต้นระบบ Multi-Worker TTS (%d workers)\n", cfg.Workers)
 
 	// สร้าง folders ที่จำเป็น
-	outputDir := "output"
+	outputDir := cfg.OutputDir
 	err := ensureDir(outputDir)
 	if err != nil {
 		panic("ไม่สามารถสร้าง output folder: " + err.Error())
 	}
 
-	// หาไฟล์ข้อความทั้งหมดใน chapters
-	chaptersDir := "chapters"
-	pattern := filepath.Join(chaptersDir, "*.txt")
-	files, err := filepath.Glob(pattern)
+	// encoder ของโปรไฟล์อื่นนอกจาก MP3 อาจไม่มีใน ffmpeg บาง build (เช่น libfdk_aac)
+	if profile := cfg.outputProfile(); profile.Name != defaultOutputProfile {
+		if err := checkEncoder(profile.Codec); err != nil {
+			fmt.Printf("❌ ใช้โปรไฟล์ %s ไม่ได้: %s\n", profile.Name, err.Error())
+			return 1
+		}
+		fmt.Printf("🎚️ โปรไฟล์ output: %s (%s)\n", profile.Name, profile.Codec)
+	}
+
+	// เพิ่มคำเฉพาะของผู้ใช้ลงพจนานุกรมตัดคำก่อนแบ่งข้อความ
+	if cfg.Dictionary != "" {
+		added, err := loadUserDictionary(cfg.Dictionary)
+		if err != nil {
+			fmt.Printf("❌ %s\n", err.Error())
+			return 1
+		}
+		fmt.Printf("📖 เพิ่มคำจาก %s %d คำ\n", cfg.Dictionary, added)
+	}
+
+	// หาไฟล์ข้อความทั้งหมดใน input folder
+	files, err := findInputFiles(cfg.InputDir, cfg.Glob)
 	if err != nil || len(files) == 0 {
-		panic("ไม่พบไฟล์ .txt ใน folder chapters")
+		panic(fmt.Sprintf("ไม่พบไฟล์ %s ใน folder %s", cfg.Glob, cfg.InputDir))
 	}
 
 	// เรียงลำดับไฟล์
@@ -696,73 +723,219 @@ func main() {
 		fmt.Printf("   %d. %s\n", i+1, filepath.Base(file))
 	}
 
-	// สร้าง context สำหรับ Google Cloud TTS
-	ctx := context.Background()
-	client, err := texttospeech.NewClient(ctx)
-	useCloudTTS := (err == nil)
-	if useCloudTTS {
-		defer client.Close()
-		fmt.Println("✅ ใช้ Google Cloud TTS")
-	} else {
-		fmt.Println("⚠️ ไม่สามารถเชื่อมต่อ Google Cloud TTS, ใช้ Google Translate TTS แทน")
-	}
-
 	// อ่านไฟล์ทั้งหมดและสร้าง jobs
 	var jobs []TTSJob
-	for i, file := range files {
-		// อ่านเนื้อหาไฟล์
-		data, err := os.ReadFile(file)
-		if err != nil {
+	var badFiles []string
+	lexicons := map[string]*lexicon{} // key = lexicon เฉพาะบท
+	for _, file := range files {
+		// อ่านเนื้อหาไฟล์เป็นบท (EPUB ได้หลายบท) และแปลงเป็น UTF-8
+		chapters, err := readInputChapters(cfg, file, cfg.Encoding)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
 			fmt.Printf("❌ ไม่สามารถอ่านไฟล์ %s: %s\n", file, err.Error())
 			continue
 		}
-
-		text := strings.TrimSpace(string(data))
-		if text == "" {
-			fmt.Printf("⚠️ ไฟล์ %s ว่างเปล่า\n", file)
+		if err != nil {
+			badFiles = append(badFiles, fmt.Sprintf("%s: %v", file, err))
 			continue
 		}
+		if len(chapters) > 1 {
+			fmt.Printf("📖 %s มี %d บท\n", filepath.Base(file), len(chapters))
+		}
 
-		// สร้างชื่อไฟล์ output
-		baseName := strings.TrimSuffix(filepath.Base(file), ".txt")
-		outputFile := filepath.Join(outputDir, baseName+".mp3")
+		for _, chapter := range chapters {
+			if chapter.Encoding != encodingUTF8 {
+				fmt.Printf("🔤 แปลง %s จาก %s เป็น UTF-8\n", filepath.Base(file), chapter.Encoding)
+			}
+			if chapter.Book != nil {
+				err = chapter.Book.saveCover(outputDir, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+				if err != nil {
+					fmt.Printf("⚠️ ไม่สามารถบันทึกรูปปกของ %s: %s\n", filepath.Base(file), err.Error())
+					chapter.Book.Cover = nil
+				}
+			}
+
+			text := strings.TrimSpace(chapter.Text)
+			if text == "" {
+				fmt.Printf("⚠️ ไฟล์ %s ว่างเปล่า\n", chapter.FilePath)
+				continue
+			}
+
+			// สร้างชื่อไฟล์ output
+			baseName := chapter.BaseName
+			outputFile := filepath.Join(outputDir, baseName+"."+cfg.outputProfile().Extension)
+
+			// lexicon หลักรวมกับ lexicon เฉพาะบท (โหลดครั้งเดียวต่อชุด)
+			chapterLexicon := cfg.Chapters[baseName].Lexicon
+			lex, ok := lexicons[chapterLexicon]
+			if !ok {
+				lex, err = loadLexicons(cfg.cleaner, cfg.Lexicon, chapterLexicon)
+				if err != nil {
+					fmt.Printf("❌ %s\n", err.Error())
+					return 1
+				}
+				lexicons[chapterLexicon] = lex
+			}
+
+			job := TTSJob{
+				ID:         len(jobs) + 1,
+				FilePath:   chapter.FilePath,
+				OutputPath: outputFile,
+				Text:       text,
+				SSML:       chapter.SSML,
+				Voice:      cfg.voiceFor(baseName),
+				Lexicon:    lex,
+				Book:       chapter.Book,
+			}
 
-		job := TTSJob{
-			ID:         i + 1,
-			FilePath:   file,
-			OutputPath: outputFile,
-			Text:       text,
+			// ชื่อบทจากสารบัญหรือบรรทัดแรก: เก็บเป็น metadata และประกาศก่อนเนื้อหาหากเปิด announce_title
+			if !job.SSML {
+				title, rest, ok := detectChapterTitle(text, chapterNumber(job))
+				if chapter.Title != "" {
+					title, rest = tocChapterTitle(chapter.Title, text, chapterNumber(job))
+					ok = true
+				}
+				if ok {
+					job.Title = title.Display
+					if cfg.AnnounceTitle && strings.TrimSpace(rest) != "" {
+						job.Text = strings.TrimSpace(rest)
+						job.Announcement = cfg.announcement(title, lex)
+					}
+				}
+			}
+			jobs = append(jobs, job)
 		}
-		jobs = append(jobs, job)
+	}
+
+	// ไฟล์ที่แปลงเป็นข้อความไม่ได้ (การเข้ารหัสไม่รองรับหรือ EPUB เสีย) ต้องแก้ก่อน
+	// ไม่เช่นนั้นจะได้เสียงอ่านอักษรเพี้ยนหรือขาดบท
+	if len(badFiles) > 0 {
+		fmt.Printf("❌ ไฟล์ %d ไฟล์แปลงเป็นข้อความไม่ได้:\n", len(badFiles))
+		for _, line := range badFiles {
+			fmt.Printf("   - %s\n", line)
+		}
+		fmt.Printf("💡 ไฟล์ข้อความต้องเป็น %s (หรือระบุ --encoding)\n", strings.Join(supportedEncodings[1:], ", "))
+		return 1
 	}
 
 	if len(jobs) == 0 {
 		fmt.Println("❌ ไม่มีไฟล์ที่สามารถประมวลผลได้")
-		return
+		return 0
+	}
+
+	// ข้ามบทที่ไม่ได้เลือก และบทที่ output เป็นปัจจุบันตาม manifest
+	manifest, err := loadManifest(outputDir)
+	if err != nil {
+		fmt.Printf("⚠️ %s, สร้าง manifest ใหม่\n", err.Error())
+		manifest = &BuildManifest{Version: manifestVersion, Chapters: map[string]ManifestEntry{}}
+	}
+	allJobs := jobs
+	for i := range jobs {
+		jobs[i].Total = len(jobs)
+	}
+	var pending []TTSJob
+	skipped := 0
+	for _, job := range jobs {
+		if !cfg.selector.matches(job) {
+			continue
+		}
+		if !cfg.Force && manifest.upToDate(job, settingsHashFor(cfg, job)) {
+			fmt.Printf("⏭️ ข้าม %s (เป็นปัจจุบัน)\n", filepath.Base(job.FilePath))
+			skipped++
+			continue
+		}
+		pending = append(pending, job)
+	}
+	jobs = pending
+
+	if len(jobs) == 0 {
+		fmt.Printf("✅ ไม่มีบทที่ต้องสร้างใหม่ (ข้าม %d บทที่เป็นปัจจุบัน)\n", skipped)
+		if cfg.Book {
+			return writeAudiobook(ctx, cfg, allJobs)
+		}
+		return 0
+	}
+
+	// เลือก engine ตามลำดับ fallback
+	engineNames, err := parseEngineChain(cfg.Engines)
+	if err != nil {
+		panic("ตั้งค่า engine ไม่ถูกต้อง: " + err.Error())
+	}
+
+	chain := buildSynthesizerChain(ctx, cfg, engineNames)
+	defer closeSynthesizerChain(chain)
+	if len(chain) == 0 {
+		fmt.Println("❌ ไม่มี engine ที่ใช้งานได้")
+		return 1
+	}
+
+	// ตรวจสอบเสียงที่ใช้ทั้งหมดก่อนเริ่ม แทนที่จะล้มเหลวทีละบท
+	var voices []VoiceSettings
+	seenVoices := map[VoiceSettings]bool{}
+	for _, job := range jobs {
+		jobVoices := []VoiceSettings{job.Voice}
+		if job.Announcement != "" {
+			jobVoices = append(jobVoices, cfg.titleVoice(job.Voice))
+		}
+		if cfg.MixedLanguage {
+			for _, voice := range jobVoices {
+				jobVoices = append(jobVoices, cfg.scriptVoices(voice)...)
+			}
+		}
+		for _, voice := range jobVoices {
+			if !seenVoices[voice] {
+				seenVoices[voice] = true
+				voices = append(voices, voice)
+			}
+		}
+	}
+	err = validateChainVoices(ctx, chain, voices)
+	if err != nil {
+		fmt.Printf("❌ %s\n", err.Error())
+		return 1
 	}
 
-	fmt.Printf("🎯 เตรียมประมวลผล %d งาน ด้วย %d workers\n", len(jobs), NUM_WORKERS)
+	// เปิด cache ของเสียงแต่ละส่วน
+	var cache *chunkCache
+	if !cfg.NoCache {
+		maxSize, _ := parseByteSize(cfg.CacheMaxSize)
+		cache, err = openChunkCache(cfg.CacheDir, maxSize)
+		if err != nil {
+			fmt.Printf("⚠️ ไม่ใช้ cache: %s\n", err.Error())
+			cache = nil
+		} else {
+			fmt.Printf("📦 ใช้ cache: %s\n", cfg.CacheDir)
+		}
+	}
+
+	fmt.Printf("🎯 เตรียมประมวลผล %d งาน ด้วย %d workers\n", len(jobs), cfg.Workers)
 
-	// สร้าง channels สำหรับการประสานงาน
-	jobsChan := make(chan TTSJob, len(jobs))
+	// สร้าง channels สำหรับการประสานงาน (jobsChan ไม่มี buffer เพื่อหยุดแจกงานได้ทันทีเมื่อถูกยกเลิก)
+	jobsChan := make(chan TTSJob)
 	resultsChan := make(chan TTSResult, len(jobs))
 
 	// เริ่มต้น workers
 	var wg sync.WaitGroup
-	for workerID := 1; workerID <= NUM_WORKERS; workerID++ {
+	for workerID := 1; workerID <= cfg.Workers; workerID++ {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			ttsWorker(id, jobsChan, resultsChan, client, ctx, useCloudTTS, AUDIO_SPEED_MULTIPLIER)
+			ttsWorker(ctx, cfg, cache, id, jobsChan, resultsChan, chain)
 		}(workerID)
 	}
 
-	// ส่งงานทั้งหมดลง channel
+	// ส่งงานทั้งหมดลง channel จนกว่าจะถูกยกเลิก
 	startTime := time.Now()
-	for _, job := range jobs {
-		jobsChan <- job
-	}
-	close(jobsChan)
+	go func() {
+		defer close(jobsChan)
+		for _, job := range jobs {
+			select {
+			case jobsChan <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
 	// รอให้ workers เสร็จสิ้น
 	go func() {
@@ -772,15 +945,40 @@ func main() {
 
 	// รับผลลัพธ์
 	var results []TTSResult
-	var successCount, failCount int
+	var successCount, failCount, canceledCount, partialCount int
 	var totalSize int64
+	finished := map[string]bool{}
 
 	fmt.Println("\n📊 กำลังรับผลลัพธ์...")
 	for result := range resultsChan {
 		results = append(results, result)
+		if result.Canceled {
+			canceledCount++
+			continue
+		}
+		finished[result.Job.OutputPath] = true
 		if result.Success {
 			successCount++
 			totalSize += result.Size
+
+			// บทที่ขาดบางส่วนไม่บันทึกใน manifest เพื่อให้สร้างใหม่ในรอบถัดไป
+			if len(result.Missing) > 0 {
+				partialCount++
+				fmt.Printf("⚠️ เสร็จบางส่วน: %s (%.1f KB) ขาดส่วนที่ %s\n",
+					filepath.Base(result.Job.FilePath),
+					float64(result.Size)/1024,
+					formatChunkList(result.Missing))
+				continue
+			}
+
+			// บันทึก manifest ทันทีเพื่อให้รันต่อได้หากโปรแกรมหยุดกลางคัน
+			err := manifest.record(result.Job, settingsHashFor(cfg, result.Job), result.Engine)
+			if err == nil {
+				err = manifest.save(outputDir)
+			}
+			if err != nil {
+				fmt.Printf("⚠️ ไม่สามารถบันทึก manifest: %s\n", err.Error())
+			}
 			fmt.Printf("✅ เสร็จสิ้น: %s (%.1f KB)\n",
 				filepath.Base(result.Job.FilePath),
 				float64(result.Size)/1024)
@@ -794,10 +992,29 @@ func main() {
 
 	duration := time.Since(startTime)
 
+	// ถูกยกเลิก: บทที่เสร็จแล้วอยู่ใน manifest จึงถูกข้ามเมื่อรันใหม่ ออกด้วย exit code เฉพาะ
+	if ctx.Err() != nil {
+		unfinished := 0
+		for _, job := range jobs {
+			if !finished[job.OutputPath] {
+				unfinished++
+			}
+		}
+		fmt.Printf("\n🛑 ถูกยกเลิก: สำเร็จ %d, ล้มเหลว %d, ยังไม่เสร็จ %d ไฟล์ (ยกเลิกระหว่างทำ %d)\n", successCount, failCount, unfinished, canceledCount)
+		fmt.Println("💡 รันคำสั่งเดิมอีกครั้งเพื่อทำต่อ บทที่เสร็จแล้วจะถูกข้าม")
+		return exitInterrupted
+	}
+
 	// แสดงสรุปผลลัพธ์
 	fmt.Printf("\n🎉 ประมวลผลเสร็จสิ้นทั้งหมด!\n")
 	fmt.Printf("⏱️  เวลาที่ใช้: %.1f วินาที\n", duration.Seconds())
 	fmt.Printf("✅ สำเร็จ: %d ไฟล์\n", successCount)
+	if skipped > 0 {
+		fmt.Printf("⏭️ ข้าม (เป็นปัจจุบัน): %d ไฟล์\n", skipped)
+	}
+	if partialCount > 0 {
+		fmt.Printf("⚠️ ขาดบางส่วน: %d ไฟล์ (จะสร้างใหม่ในรอบถัดไป)\n", partialCount)
+	}
 	fmt.Printf("❌ ล้มเหลว: %d ไฟล์\n", failCount)
 	fmt.Printf("📁 ไฟล์เสียงทั้งหมดอยู่ใน folder: %s\n", outputDir)
 	fmt.Printf("💾 ขนาดไฟล์รวม: %.1f MB\n", float64(totalSize)/(1024*1024))
@@ -805,7 +1022,7 @@ func main() {
 	// แสดงรายการไฟล์ที่สร้างขึ้น
 	if successCount > 0 {
 		fmt.Println("\n📄 ไฟล์ที่สร้างขึ้น:")
-		outputPattern := filepath.Join(outputDir, "*.mp3")
+		outputPattern := filepath.Join(outputDir, "*."+cfg.outputProfile().Extension)
 		outputFiles, err := filepath.Glob(outputPattern)
 		if err == nil && len(outputFiles) > 0 {
 			sort.Strings(outputFiles)
@@ -817,6 +1034,15 @@ func main() {
 		}
 	}
 
+	if partialCount > 0 {
+		fmt.Println("\n⚠️  ไฟล์ที่ขาดบางส่วน:")
+		for _, result := range results {
+			if result.Success && len(result.Missing) > 0 {
+				fmt.Printf("   - %s: ส่วนที่ %s\n", filepath.Base(result.Job.FilePath), formatChunkList(result.Missing))
+			}
+		}
+	}
+
 	if failCount > 0 {
 		fmt.Println("\n⚠️  ไฟล์ที่ล้มเหลว:")
 		for _, result := range results {
@@ -826,5 +1052,15 @@ func main() {
 		}
 	}
 
+	// หนังสือเสียงต้องมีครบทุกบท
+	if cfg.Book {
+		if failCount > 0 || partialCount > 0 {
+			fmt.Println("\n⚠️ ไม่สร้างหนังสือเสียงเพราะยังมีบทที่ล้มเหลวหรือขาดบางส่วน")
+		} else if code := writeAudiobook(ctx, cfg, allJobs); code != 0 {
+			return code
+		}
+	}
+
 	fmt.Printf("\n🏁 Multi-Worker TTS เสร็จสิ้น!\n")
+	return 0
 }
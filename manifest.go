@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ชื่อไฟล์ manifest ที่เก็บไว้ใน output folder
+const manifestFileName = "manifest.json"
+
+// เวอร์ชันของรูปแบบ manifest
+const manifestVersion = 1
+
+// บันทึกการสร้างไฟล์เสียงแต่ละบท ใช้ตัดสินว่าบทไหนต้องสร้างใหม่
+type BuildManifest struct {
+	Version  int                      `json:"version"`
+	Chapters map[string]ManifestEntry `json:"chapters"` // key = ชื่อไฟล์ output
+}
+
+// ข้อมูลการสร้างของบทหนึ่ง
+type ManifestEntry struct {
+	Source       string    `json:"source"`
+	SourceHash   string    `json:"source_hash"`
+	SettingsHash string    `json:"settings_hash"`
+	Engine       string    `json:"engine"`
+	Title        string    `json:"title,omitempty"` // ชื่อบทใน tag (เปลี่ยนแล้วต้องสร้างใหม่)
+	OutputHash   string    `json:"output_hash"`
+	Duration     float64   `json:"duration_seconds"`
+	BuiltAt      time.Time `json:"built_at"`
+}
+
+// การตั้งค่าที่มีผลต่อเสียงของบท (เปลี่ยนค่าใดค่าหนึ่งแล้วต้องสร้างใหม่)
+type chapterSettings struct {
+	Engines        string
+	Voice          VoiceSettings
+	Speed          float64
+	Bitrate        string
+	ChunkSize      int
+	ParagraphPause string
+	SentencePause  string `json:",omitempty"` // ว่างเมื่อไม่เว้นช่วงเงียบระหว่างประโยค
+	Dictionary     string `json:",omitempty"` // hash ของพจนานุกรมผู้ใช้ (มีผลต่อจุดแบ่งข้อความ)
+	Lexicon        string `json:",omitempty"` // hash ของ lexicon ของบท
+	Cleaning       string `json:",omitempty"` // hash ของกฎทำความสะอาด (ว่าง = ชุดกฎ novel ตามค่าเริ่มต้น)
+	Announcement   string `json:",omitempty"` // ข้อความ เสียง และช่วงเงียบของการประกาศชื่อบท
+	Languages      string `json:",omitempty"` // การแยกช่วงภาษา (ว่าง = ไม่แยก)
+	Subtitles      string `json:",omitempty"` // รูปแบบคำบรรยายที่เขียนข้างไฟล์เสียง (ว่าง = ไม่เขียน)
+	Profile        string `json:",omitempty"` // รูปแบบไฟล์ output (ว่าง = mp3 ตามค่าเริ่มต้น)
+	Metadata       string `json:",omitempty"` // ชื่อหนังสือ ผู้แต่ง และ hash ของรูปปกที่ใส่ใน tag
+}
+
+// อ่าน manifest จาก output folder (ไม่มีไฟล์ = manifest ว่าง)
+func loadManifest(outputDir string) (*BuildManifest, error) {
+	manifest := &BuildManifest{Version: manifestVersion, Chapters: map[string]ManifestEntry{}}
+	data, err := os.ReadFile(filepath.Join(outputDir, manifestFileName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ไม่สามารถอ่าน manifest: %v", err)
+	}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("manifest ไม่ถูกต้อง: %v", err)
+	}
+	if manifest.Version != manifestVersion || manifest.Chapters == nil {
+		// รูปแบบเก่า: เริ่มใหม่ทั้งหมด
+		return &BuildManifest{Version: manifestVersion, Chapters: map[string]ManifestEntry{}}, nil
+	}
+	return manifest, nil
+}
+
+// บันทึก manifest ลง output folder (เขียนไฟล์ชั่วคราวก่อนแล้ว rename)
+func (m *BuildManifest) save(outputDir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(outputDir, manifestFileName)
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// ตรวจสอบว่าไฟล์ output ของงานยังเป็นปัจจุบัน
+func (m *BuildManifest) upToDate(job TTSJob, settingsHash string) bool {
+	entry, ok := m.Chapters[filepath.Base(job.OutputPath)]
+	if !ok {
+		return false
+	}
+	if entry.SourceHash != hashString(job.Text) || entry.SettingsHash != settingsHash || entry.Title != job.Title {
+		return false
+	}
+	outputHash, err := hashFile(job.OutputPath)
+	return err == nil && outputHash == entry.OutputHash
+}
+
+// บันทึกผลการสร้างไฟล์ output ของงาน
+func (m *BuildManifest) record(job TTSJob, settingsHash, engine string) error {
+	outputHash, err := hashFile(job.OutputPath)
+	if err != nil {
+		return err
+	}
+	duration, err := probeDuration(job.OutputPath)
+	if err != nil {
+		fmt.Printf("⚠️ ไม่สามารถอ่านความยาวเสียง %s: %s\n", filepath.Base(job.OutputPath), err.Error())
+	}
+
+	m.Chapters[filepath.Base(job.OutputPath)] = ManifestEntry{
+		Source:       job.FilePath,
+		SourceHash:   hashString(job.Text),
+		SettingsHash: settingsHash,
+		Engine:       engine,
+		Title:        job.Title,
+		OutputHash:   outputHash,
+		Duration:     duration,
+		BuiltAt:      time.Now(),
+	}
+	return nil
+}
+
+// hash ของการตั้งค่าที่มีผลต่อเสียงของงาน
+func settingsHashFor(cfg *Config, job TTSJob) string {
+	settings := chapterSettings{
+		Engines:        cfg.Engines,
+		Voice:          job.Voice,
+		Speed:          cfg.Speed,
+		Bitrate:        cfg.Bitrate,
+		ChunkSize:      cfg.ChunkSize,
+		ParagraphPause: cfg.ParagraphPause,
+		Lexicon:        job.Lexicon.hash(),
+	}
+	if job.Announcement != "" {
+		settings.Announcement = strings.Join([]string{job.Announcement, cfg.TitleTemplate, cfg.titleVoice(job.Voice).Name, cfg.TitlePause}, "|")
+	}
+	if pauseDuration(cfg.SentencePause) > 0 {
+		settings.SentencePause = cfg.SentencePause
+	}
+	if cfg.MixedLanguage {
+		languages, _ := json.Marshal(struct {
+			MinRun int
+			Voices map[string]ScriptVoice
+		}{cfg.MinLanguageRun, cfg.ScriptVoices})
+		settings.Languages = string(languages)
+	}
+	if cfg.Dictionary != "" {
+		settings.Dictionary, _ = hashFile(cfg.Dictionary)
+	}
+	if cfg.CleanPreset != "novel" || len(cfg.CleanRules) > 0 {
+		rules, _ := json.Marshal(struct {
+			Preset string
+			Rules  []CleanRule
+		}{cfg.CleanPreset, cfg.CleanRules})
+		settings.Cleaning = hashString(string(rules))
+	}
+	settings.Subtitles = strings.Join(cfg.subtitleFormats(), ",")
+	if meta := cfg.bookMetadata([]TTSJob{job}); meta != (audioMetadata{}) {
+		cover, _ := hashFile(meta.Cover)
+		settings.Metadata = strings.Join([]string{meta.Album, meta.Artist, cover}, "|")
+	}
+	if cfg.Profile != defaultOutputProfile {
+		settings.Profile = cfg.Profile
+	}
+	data, _ := json.Marshal(settings)
+	return hashString(string(data))
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// อ่านความยาวของไฟล์เสียง (วินาที) ด้วย ffprobe
+func probeDuration(path string) (float64, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe error: %v", err)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+}
+
+// ช่วงหมายเลขบทที่เลือก เช่น "012-020,025"
+type chapterSelector struct {
+	ranges [][2]int
+}
+
+// แยกรูปแบบการเลือกบท (ว่าง = เลือกทั้งหมด)
+func parseChapterSelector(spec string) (*chapterSelector, error) {
+	selector := &chapterSelector{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("รูปแบบการเลือกบทไม่ถูกต้อง: %q", part)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("รูปแบบการเลือกบทไม่ถูกต้อง: %q", part)
+			}
+		}
+		selector.ranges = append(selector.ranges, [2]int{start, end})
+	}
+	return selector, nil
+}
+
+var chapterNumberPattern = regexp.MustCompile(`\d+`)
+
+// หมายเลขบทจากชื่อไฟล์ (ตัวเลขชุดแรก) หรือลำดับของงานหากชื่อไฟล์ไม่มีตัวเลข
+func chapterNumber(job TTSJob) int {
+	match := chapterNumberPattern.FindString(filepath.Base(job.FilePath))
+	if n, err := strconv.Atoi(match); err == nil {
+		return n
+	}
+	return job.ID
+}
+
+// ตรวจสอบว่างานอยู่ในช่วงที่เลือก (nil = เลือกทั้งหมด)
+func (s *chapterSelector) matches(job TTSJob) bool {
+	if s == nil || len(s.ranges) == 0 {
+		return true
+	}
+	n := chapterNumber(job)
+	for _, r := range s.ranges {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseChapterSelector(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    [][2]int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"5", [][2]int{{5, 5}}, false},
+		{"012-020,025", [][2]int{{12, 20}, {25, 25}}, false},
+		{" 1 - 3 , ,7", [][2]int{{1, 3}, {7, 7}}, false},
+		{"abc", nil, true},
+		{"20-10", nil, true},
+		{"1-x", nil, true},
+		{"-5", nil, true},
+		{"3-", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseChapterSelector(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseChapterSelector(%q) = %v, want error", tt.spec, got.ranges)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseChapterSelector(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.ranges, tt.want) {
+			t.Errorf("parseChapterSelector(%q) = %v, want %v", tt.spec, got.ranges, tt.want)
+		}
+	}
+}
+
+func TestChapterSelectorMatches(t *testing.T) {
+	selector, err := parseChapterSelector("012-020,025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		job  TTSJob
+		want bool
+	}{
+		{TTSJob{FilePath: "chapters/012.txt"}, true},
+		{TTSJob{FilePath: "chapters/020.txt"}, true},
+		{TTSJob{FilePath: "chapters/021.txt"}, false},
+		{TTSJob{FilePath: "chapters/025.md"}, true},
+		// ตัวเลขชุดแรกของชื่อไฟล์ ไม่ใช่ของ folder
+		{TTSJob{FilePath: "book2/chapter_15_part2.txt"}, true},
+		{TTSJob{FilePath: "vol30/chapter_11.txt"}, false},
+		// ชื่อไฟล์ที่ไม่มีตัวเลขใช้ลำดับงาน
+		{TTSJob{FilePath: "prologue.txt", ID: 13}, true},
+		{TTSJob{FilePath: "epilogue.txt", ID: 99}, false},
+	}
+	for _, tt := range tests {
+		if got := selector.matches(tt.job); got != tt.want {
+			t.Errorf("matches(%s, id %d) = %v, want %v", tt.job.FilePath, tt.job.ID, got, tt.want)
+		}
+	}
+
+	// ไม่ระบุ --only (หรือ config ที่ยังไม่ผ่าน validate) เลือกทุกบท
+	var none *chapterSelector
+	if !none.matches(TTSJob{FilePath: "001.txt"}) {
+		t.Error("nil selector does not match")
+	}
+}
+
+func TestManifestUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := defaultConfig()
+	job := TTSJob{
+		ID:         1,
+		FilePath:   "chapters/001.txt",
+		OutputPath: filepath.Join(dir, "001.mp3"),
+		Text:       "เนื้อหาบทที่หนึ่ง",
+		Title:      "บทที่ 1 เริ่มต้น",
+		Voice:      cfg.voiceFor("001"),
+	}
+	if err := os.WriteFile(job.OutputPath, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settingsHash := settingsHashFor(&cfg, job)
+	outputHash, err := hashFile(job.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := &BuildManifest{Version: manifestVersion, Chapters: map[string]ManifestEntry{
+		"001.mp3": {
+			Source:       job.FilePath,
+			SourceHash:   hashString(job.Text),
+			SettingsHash: settingsHash,
+			Title:        job.Title,
+			OutputHash:   outputHash,
+		},
+	}}
+
+	withMetadata := cfg
+	withMetadata.BookTitle = "ดาบพิฆาต"
+	withSpeed := cfg
+	withSpeed.Speed = 2
+
+	changed := func(change func(*TTSJob)) TTSJob {
+		changedJob := job
+		change(&changedJob)
+		return changedJob
+	}
+	tests := []struct {
+		name     string
+		job      TTSJob
+		settings string
+		want     bool
+	}{
+		{"unchanged", job, settingsHash, true},
+		{"text", changed(func(j *TTSJob) { j.Text += " แก้ไข" }), settingsHash, false},
+		{"title", changed(func(j *TTSJob) { j.Title = "บทที่ 1 ชื่อใหม่" }), settingsHash, false},
+		{"settings", job, settingsHashFor(&withSpeed, job), false},
+		{"metadata", job, settingsHashFor(&withMetadata, job), false},
+		{"voice", changed(func(j *TTSJob) { j.Voice.Name = "th-TH-Standard-A" }), "", false},
+		{"not in manifest", changed(func(j *TTSJob) { j.OutputPath = filepath.Join(dir, "002.mp3") }), settingsHash, false},
+	}
+	for _, tt := range tests {
+		settings := tt.settings
+		if settings == "" {
+			settings = settingsHashFor(&cfg, tt.job)
+		}
+		if got := manifest.upToDate(tt.job, settings); got != tt.want {
+			t.Errorf("%s: upToDate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// ไฟล์ output ที่ถูกแก้ไขหรือลบต้องสร้างใหม่
+	if err := os.WriteFile(job.OutputPath, []byte("other audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.upToDate(job, settingsHash) {
+		t.Error("upToDate with a modified output = true, want false")
+	}
+	if err := os.Remove(job.OutputPath); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.upToDate(job, settingsHash) {
+		t.Error("upToDate with a missing output = true, want false")
+	}
+}
+
+func TestManifestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	manifest := &BuildManifest{Version: manifestVersion, Chapters: map[string]ManifestEntry{
+		"001.mp3": {Source: "chapters/001.txt", SourceHash: "a", SettingsHash: "b", Engine: "translate", OutputHash: "c", Duration: 12.5},
+	}}
+	if err := manifest.save(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, manifest) {
+		t.Errorf("loadManifest = %+v, want %+v", loaded, manifest)
+	}
+
+	// รูปแบบเก่าเริ่มใหม่ทั้งหมด และไฟล์เสียเป็นข้อผิดพลาด
+	os.WriteFile(filepath.Join(dir, manifestFileName), []byte(`{"version": 0, "chapters": {"001.mp3": {}}}`), 0644)
+	if loaded, err := loadManifest(dir); err != nil || len(loaded.Chapters) != 0 {
+		t.Errorf("loadManifest of an old version = %+v, %v, want empty", loaded, err)
+	}
+	os.WriteFile(filepath.Join(dir, manifestFileName), []byte(`{`), 0644)
+	if _, err := loadManifest(dir); err == nil {
+		t.Error("loadManifest of invalid JSON succeeded, want error")
+	}
+}
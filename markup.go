@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// เครื่องหมายส่วนที่เน้น (emphasis) ในข้อความที่แยกจาก markup
+// ใช้อักษร private use จึงผ่านกฎทำความสะอาดได้ และแปลงเป็น <emphasis> ของ SSML ใน prepareJobText
+const (
+	emphasisStart = '\uE000'
+	emphasisEnd   = '\uE001'
+)
+
+// ข้อความที่แยกจากเอกสาร HTML หรือ Markdown
+type markupText struct {
+	Title      string   // หัวข้อที่ขึ้นต้นเอกสาร (ว่าง = ไม่มี)
+	Paragraphs []string // ข้อความย่อหน้าละรายการ ส่วนที่เน้นอยู่ระหว่าง emphasisStart และ emphasisEnd
+}
+
+// ข้อความที่อ่านแทนส่วนที่อ่านออกเสียงไม่ได้ (ว่าง = ข้าม)
+type markupPhrases struct {
+	Code  string
+	Image string // {alt} = คำอธิบายรูป
+}
+
+// ข้อความแทนส่วนที่อ่านไม่ได้จากการตั้งค่า
+func (c *Config) markupPhrases() markupPhrases {
+	return markupPhrases{Code: c.CodePhrase, Image: c.ImagePhrase}
+}
+
+// ข้อความที่อ่านแทนรูปภาพ
+func (p markupPhrases) image(alt string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(p.Image, "{alt}", alt)), " ")
+}
+
+// เพิ่มย่อหน้า (ข้ามย่อหน้าที่ไม่มีข้อความนอกจากเครื่องหมายเน้น)
+func (m *markupText) addParagraph(text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.ReplaceAll(text, string(emphasisStart)+string(emphasisEnd), "")
+	if stripEmphasis(text) != "" {
+		m.Paragraphs = append(m.Paragraphs, text)
+	}
+}
+
+// เพิ่มหัวข้อเป็นย่อหน้า หัวข้อที่ขึ้นต้นเอกสารเป็นชื่อบท
+func (m *markupText) addHeading(text string) {
+	if len(m.Paragraphs) == 0 && m.Title == "" {
+		m.Title = strings.Join(strings.Fields(stripEmphasis(text)), " ")
+	}
+	m.addParagraph(text)
+}
+
+// ข้อความย่อหน้าละบรรทัด
+func (m markupText) String() string {
+	return strings.Join(m.Paragraphs, "\n")
+}
+
+// ลบเครื่องหมายเน้นออกจากข้อความ
+func stripEmphasis(text string) string {
+	return strings.NewReplacer(string(emphasisStart), "", string(emphasisEnd), "").Replace(text)
+}
+
+// ตรวจสอบว่าข้อความมีส่วนที่เน้น
+func hasEmphasis(text string) bool {
+	return strings.ContainsRune(text, emphasisStart)
+}
+
+// ครอบ render ให้แปลงเครื่องหมายเน้นเป็น <emphasis>
+// ส่วนที่เน้นข้ามประโยคจะถูกปิดท้ายและเปิดใหม่ในการเรียกครั้งถัดไป เพื่อให้ tag ครบคู่ทุกครั้ง
+func emphasisRenderer(render func(string) string) func(string) string {
+	depth := 0
+	return func(text string) string {
+		var sb strings.Builder
+		if depth > 0 {
+			sb.WriteString("<emphasis>")
+		}
+		for text != "" {
+			i := strings.IndexAny(text, string(emphasisStart)+string(emphasisEnd))
+			if i < 0 {
+				sb.WriteString(render(text))
+				break
+			}
+			sb.WriteString(render(text[:i]))
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if r == emphasisStart {
+				if depth == 0 {
+					sb.WriteString("<emphasis>")
+				}
+				depth++
+			} else if depth > 0 {
+				depth--
+				if depth == 0 {
+					sb.WriteString("</emphasis>")
+				}
+			}
+			text = text[i+size:]
+		}
+		if depth > 0 {
+			sb.WriteString("</emphasis>")
+		}
+		return sb.String()
+	}
+}
+
+// element ที่เริ่มย่อหน้าใหม่
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"td": true, "th": true, "tr": true, "ul": true,
+}
+
+// element หัวข้อ
+var htmlHeadingElements = map[string]bool{"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true}
+
+// element ที่อ่านแบบเน้นเสียง
+var htmlEmphasisElements = map[string]bool{"em": true, "i": true, "strong": true, "b": true, "mark": true}
+
+// element ที่ไม่มีข้อความสำหรับอ่าน (nav = สารบัญหรือเมนูนำทาง, rt = คำอ่านกำกับของ ruby)
+var htmlSkipElements = map[string]bool{
+	"head": true, "script": true, "style": true, "nav": true, "svg": true, "math": true, "rt": true, "rp": true,
+}
+
+var (
+	// <script> และ <style> ของ HTML มีอักษร < และ & ที่ไม่ใช่ markup จึงตัดออกก่อนแยก tag
+	htmlRawTextPattern = regexp.MustCompile(`(?is)<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+	// < ที่ไม่ได้เริ่ม tag เช่น "x < y" ใน HTML ที่ไม่ใช่ XHTML
+	htmlStrayLessPattern = regexp.MustCompile(`<([^A-Za-z/!?])`)
+)
+
+// decoder สำหรับ XHTML/HTML: ยอมรับ tag ที่ไม่ปิด และ entity ของ HTML
+// ไฟล์ที่ประกาศการเข้ารหัสอื่นใน <?xml?> จะถูกแปลงเป็น UTF-8 ก่อน (เว้นแต่เป็น UTF-8 อยู่แล้ว)
+func newMarkupDecoder(r io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		if utf8.Valid(data) {
+			return bytes.NewReader(data), nil
+		}
+		text, _, err := decodeText(data, charset)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(text), nil
+	}
+	return dec
+}
+
+// ค่า attribute ตามชื่อ (ไม่สนใจ namespace เช่น epub:type)
+func markupAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// แยกข้อความของเอกสาร HTML/XHTML เป็นย่อหน้า โดยตัด markup ทิ้ง
+// ลิงก์อ่านเฉพาะข้อความ <pre> และรูปภาพอ่านด้วยข้อความแทน และ <em>/<strong> เป็นส่วนที่เน้น
+func parseHTML(data []byte, phrases markupPhrases) (markupText, error) {
+	data = htmlRawTextPattern.ReplaceAll(data, nil)
+	data = htmlStrayLessPattern.ReplaceAll(data, []byte("&lt;$1"))
+	dec := newMarkupDecoder(bytes.NewReader(data))
+
+	var doc markupText
+	var current strings.Builder
+	heading := false
+	flush := func() {
+		if heading {
+			doc.addHeading(current.String())
+		} else {
+			doc.addParagraph(current.String())
+		}
+		current.Reset()
+	}
+
+	skip := 0 // ความลึกภายใน element ที่ข้าม
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return markupText{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if skip > 0 || htmlSkipElements[name] {
+				skip++
+				continue
+			}
+			switch {
+			case name == "pre":
+				flush()
+				doc.addParagraph(phrases.Code)
+				skip++
+			case name == "img":
+				current.WriteString(" " + phrases.image(markupAttr(t, "alt")) + " ")
+			case htmlEmphasisElements[name]:
+				current.WriteRune(emphasisStart)
+			case htmlBlockElements[name]:
+				flush()
+				heading = htmlHeadingElements[name]
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			name := strings.ToLower(t.Name.Local)
+			if htmlEmphasisElements[name] {
+				current.WriteRune(emphasisEnd)
+			} else if htmlBlockElements[name] {
+				flush()
+				heading = false
+			}
+		case xml.CharData:
+			if skip == 0 {
+				current.Write(t)
+			}
+		}
+	}
+	flush()
+	return doc, nil
+}
+
+var (
+	markdownFencePattern     = regexp.MustCompile("^(`{3,}|~{3,})")
+	markdownHeadingPattern   = regexp.MustCompile(`^(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	markdownSetextPattern    = regexp.MustCompile(`^(=+|-+)\s*$`)
+	markdownRulePattern      = regexp.MustCompile(`^([-*_])(\s*([-*_])){2,}\s*$`)
+	markdownReferencePattern = regexp.MustCompile(`^\[[^\]]+\]:\s*\S+`)
+	markdownQuotePattern     = regexp.MustCompile(`^(>\s?)+`)
+	markdownListPattern      = regexp.MustCompile(`^([-*+]|\d+[.)])\s+`)
+	markdownTagPattern       = regexp.MustCompile(`^</?([A-Za-z][A-Za-z0-9]*)\b[^>]*>`)
+	markdownAutolinkPattern  = regexp.MustCompile(`^<(?:[A-Za-z][A-Za-z0-9+.\-]*:[^\s<>]*|[^\s<>@]+@[^\s<>]+)>`)
+)
+
+// แยกข้อความของเอกสาร Markdown เป็นย่อหน้า
+// บรรทัดที่ติดกันรวมเป็นย่อหน้าเดียว (ย่อหน้าใหม่เมื่อเว้นบรรทัด หรือขึ้นบรรทัดด้วยช่องว่างสองตัวหรือ \)
+func parseMarkdown(text string, phrases markupPhrases) markupText {
+	var doc markupText
+	var paragraph []string
+	flush := func() {
+		doc.addParagraph(markdownInline(strings.Join(paragraph, " "), phrases))
+		paragraph = nil
+	}
+
+	quoted := false // บรรทัดก่อนหน้าอยู่ใน blockquote
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		trimmed := strings.TrimSpace(line)
+		// blockquote อ่านเป็นย่อหน้าธรรมดา โดยเริ่มย่อหน้าใหม่เมื่อเข้าหรือออกจาก blockquote
+		quote := markdownQuotePattern.MatchString(trimmed)
+		if quote != quoted {
+			flush()
+			quoted = quote
+		}
+		trimmed = strings.TrimSpace(markdownQuotePattern.ReplaceAllString(trimmed, ""))
+
+		switch {
+		case trimmed == "":
+			flush()
+		case markdownFencePattern.MatchString(trimmed):
+			// code block: ข้ามจนถึง fence ปิด
+			flush()
+			fence := markdownFencePattern.FindString(trimmed)
+			for i++; i < len(lines); i++ {
+				closing := strings.TrimSpace(lines[i])
+				if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+					break
+				}
+			}
+			doc.addParagraph(phrases.Code)
+		case markdownHeadingPattern.MatchString(trimmed):
+			flush()
+			doc.addHeading(markdownInline(markdownHeadingPattern.FindStringSubmatch(trimmed)[2], phrases))
+		case len(paragraph) > 0 && markdownSetextPattern.MatchString(trimmed):
+			doc.addHeading(markdownInline(strings.Join(paragraph, " "), phrases))
+			paragraph = nil
+		case markdownRulePattern.MatchString(trimmed):
+			flush()
+		case markdownReferencePattern.MatchString(trimmed):
+			// นิยามลิงก์ [ref]: url ไม่อ่าน
+		case markdownListPattern.MatchString(trimmed):
+			flush()
+			paragraph = append(paragraph, markdownListPattern.ReplaceAllString(trimmed, ""))
+		default:
+			paragraph = append(paragraph, strings.TrimSuffix(trimmed, `\`))
+			if strings.HasSuffix(line, "  ") || strings.HasSuffix(trimmed, `\`) {
+				flush()
+			}
+		}
+	}
+	flush()
+	return doc
+}
+
+// แปลง inline markup ของ Markdown เป็นข้อความ: ลิงก์อ่านเฉพาะข้อความ รูปภาพอ่านด้วยข้อความแทน
+// *เน้น* และ **เน้นมาก** เป็นส่วนที่เน้น และ entity ของ HTML ถูกแปลงเป็นตัวอักษร
+func markdownInline(text string, phrases markupPhrases) string {
+	return html.UnescapeString(markdownSpans([]rune(text), phrases))
+}
+
+func markdownSpans(runes []rune, phrases markupPhrases) string {
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && (unicode.IsPunct(runes[i+1]) || unicode.IsSymbol(runes[i+1])):
+			sb.WriteRune(runes[i+1])
+			i += 2
+			continue
+
+		case r == '`':
+			// code span: อ่านข้อความตามตัวอักษร
+			n := runLength(runes, i)
+			if end := findRun(runes, i+n, '`', n); end >= 0 {
+				sb.WriteString(strings.TrimSpace(string(runes[i+n : end])))
+				i = end + n
+				continue
+			}
+			sb.WriteString(string(runes[i : i+n]))
+			i += n
+			continue
+
+		case r == '!' && i+1 < len(runes) && runes[i+1] == '[':
+			if label, end, ok := markdownLink(runes, i+1); ok {
+				sb.WriteString(" " + phrases.image(stripEmphasis(markdownSpans(label, phrases))) + " ")
+				i = end
+				continue
+			}
+
+		case r == '[':
+			if label, end, ok := markdownLink(runes, i); ok {
+				sb.WriteString(markdownSpans(label, phrases))
+				i = end
+				continue
+			}
+
+		case r == '<':
+			rest := string(runes[i:])
+			if m := markdownAutolinkPattern.FindString(rest); m != "" {
+				i += utf8.RuneCountInString(m)
+				continue
+			}
+			if m := markdownTagPattern.FindStringSubmatch(rest); m != nil {
+				name := strings.ToLower(m[1])
+				if htmlEmphasisElements[name] {
+					if strings.HasPrefix(m[0], "</") {
+						sb.WriteRune(emphasisEnd)
+					} else {
+						sb.WriteRune(emphasisStart)
+					}
+				} else {
+					sb.WriteString(" ")
+				}
+				i += utf8.RuneCountInString(m[0])
+				continue
+			}
+
+		case r == '*' || r == '_':
+			n := runLength(runes, i)
+			if end := markdownEmphasisEnd(runes, i, n); end >= 0 {
+				sb.WriteRune(emphasisStart)
+				sb.WriteString(markdownSpans(runes[i+n:end], phrases))
+				sb.WriteRune(emphasisEnd)
+				i = end + n
+				continue
+			}
+			sb.WriteString(string(runes[i : i+n]))
+			i += n
+			continue
+		}
+		sb.WriteRune(r)
+		i++
+	}
+	return sb.String()
+}
+
+// จำนวนตัวอักษรเดียวกันที่ติดกันตั้งแต่ตำแหน่ง i
+func runLength(runes []rune, i int) int {
+	n := 1
+	for i+n < len(runes) && runes[i+n] == runes[i] {
+		n++
+	}
+	return n
+}
+
+// ตำแหน่งของชุดตัวอักษร r ยาว n ตัวพอดีตั้งแต่ from (-1 = ไม่พบ)
+func findRun(runes []rune, from int, r rune, n int) int {
+	for i := from; i < len(runes); {
+		if runes[i] != r {
+			i++
+			continue
+		}
+		m := runLength(runes, i)
+		if m == n {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+// ตำแหน่งเครื่องหมายปิดของส่วนที่เน้นซึ่งเปิดที่ i ด้วยเครื่องหมายยาว n ตัว (-1 = ไม่ใช่การเน้น)
+// _ ต้องอยู่ที่ขอบคำ เพื่อไม่ให้ชื่อเช่น snake_case_name กลายเป็นการเน้น
+func markdownEmphasisEnd(runes []rune, i, n int) int {
+	r := runes[i]
+	open := i + n
+	if open >= len(runes) || unicode.IsSpace(runes[open]) {
+		return -1
+	}
+	if r == '_' && i > 0 && isWordRune(runes[i-1]) {
+		return -1
+	}
+	for end := findRun(runes, open+1, r, n); end >= 0; end = findRun(runes, end+n, r, n) {
+		if unicode.IsSpace(runes[end-1]) {
+			continue
+		}
+		if r == '_' && end+n < len(runes) && isWordRune(runes[end+n]) {
+			continue
+		}
+		return end
+	}
+	return -1
+}
+
+// ลิงก์ [ข้อความ](url), [ข้อความ][ref] หรือ [ข้อความ] ที่เริ่มที่ i
+// คืนข้อความของลิงก์และตำแหน่งถัดจากลิงก์
+func markdownLink(runes []rune, i int) ([]rune, int, bool) {
+	closing := matchingBracket(runes, i, '[', ']')
+	if closing < 0 {
+		return nil, 0, false
+	}
+	label := runes[i+1 : closing]
+	end := closing + 1
+	if end < len(runes) && runes[end] == '(' {
+		if paren := matchingBracket(runes, end, '(', ')'); paren >= 0 {
+			end = paren + 1
+		}
+	} else if end < len(runes) && runes[end] == '[' {
+		if ref := matchingBracket(runes, end, '[', ']'); ref >= 0 {
+			end = ref + 1
+		}
+	}
+	return label, end, true
+}
+
+// ตำแหน่งวงเล็บปิดที่คู่กับวงเล็บเปิดที่ i (-1 = ไม่พบ)
+func matchingBracket(runes []rune, i int, open, closing rune) int {
+	depth := 0
+	for j := i; j < len(runes); j++ {
+		switch runes[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
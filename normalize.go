@@ -0,0 +1,310 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// คำอ่านตัวเลขหลักเดียว
+var thaiDigitWords = [...]string{"ศูนย์", "หนึ่ง", "สอง", "สาม", "สี่", "ห้า", "หก", "เจ็ด", "แปด", "เก้า"}
+
+// คำอ่านหลัก (หน่วย สิบ ร้อย พัน หมื่น แสน)
+var thaiPlaceWords = [...]string{"", "สิบ", "ร้อย", "พัน", "หมื่น", "แสน"}
+
+// ชื่อเดือนเต็ม (index 0 = มกราคม)
+var thaiMonthNames = [...]string{
+	"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม",
+}
+
+// ชื่อเดือนย่อ
+var thaiMonthAbbreviations = map[string]string{
+	"ม.ค.": "มกราคม", "ก.พ.": "กุมภาพันธ์", "มี.ค.": "มีนาคม", "เม.ย.": "เมษายน",
+	"พ.ค.": "พฤษภาคม", "มิ.ย.": "มิถุนายน", "ก.ค.": "กรกฎาคม", "ส.ค.": "สิงหาคม",
+	"ก.ย.": "กันยายน", "ต.ค.": "ตุลาคม", "พ.ย.": "พฤศจิกายน", "ธ.ค.": "ธันวาคม",
+}
+
+// หน่วยที่อ่านเป็นคำเมื่อตามหลังตัวเลข
+var thaiUnitWords = map[string]string{
+	"%":       "เปอร์เซ็นต์",
+	"กม./ชม.": "กิโลเมตรต่อชั่วโมง",
+	"km/h":    "กิโลเมตรต่อชั่วโมง",
+	"ตร.กม.":  "ตารางกิโลเมตร",
+	"ตร.ม.":   "ตารางเมตร",
+	"กม.":     "กิโลเมตร",
+	"ซม.":     "เซนติเมตร",
+	"มม.":     "มิลลิเมตร",
+	"ม.":      "เมตร",
+	"กก.":     "กิโลกรัม",
+	"มก.":     "มิลลิกรัม",
+	"มล.":     "มิลลิลิตร",
+	"ชม.":     "ชั่วโมง",
+	"km":      "กิโลเมตร",
+	"cm":      "เซนติเมตร",
+	"mm":      "มิลลิเมตร",
+	"kg":      "กิโลกรัม",
+	"mg":      "มิลลิกรัม",
+	"ml":      "มิลลิลิตร",
+	"°C":      "องศาเซลเซียส",
+	"°F":      "องศาฟาเรนไฮต์",
+	"°":       "องศา",
+}
+
+// สัญลักษณ์สกุลเงินที่เขียนไว้หน้าตัวเลข
+var thaiCurrencyWords = map[string]string{
+	"฿": "บาท",
+	"$": "ดอลลาร์",
+	"€": "ยูโร",
+	"£": "ปอนด์",
+	"¥": "เยน",
+}
+
+// กฎการแปลงข้อความหนึ่งรูปแบบ (ทำตามลำดับในตาราง)
+type normalizeRule struct {
+	name    string
+	pattern *regexp.Regexp
+	// คืนคำอ่านของข้อความที่ตรงกับรูปแบบ (m[0] = ทั้งหมด, m[1:] = กลุ่มย่อย) หรือ "" เพื่อคงข้อความเดิม
+	expand func(m []string) string
+}
+
+// รูปแบบตัวเลข เช่น 12,500 หรือ 3.14
+const numberPattern = `\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?`
+
+// ตารางกฎการแปลง (คอมไพล์ครั้งเดียว) ลำดับมีผล: รูปแบบเฉพาะต้องมาก่อนตัวเลขทั่วไป
+var thaiNormalizeRules = []normalizeRule{
+	{
+		// เบอร์โทรศัพท์ เช่น 081-234-5678, 02-123-4567, 0812345678 อ่านทีละตัว
+		name:    "phone",
+		pattern: regexp.MustCompile(`\b(0\d{1,2})-(\d{3})-(\d{3,4})\b|\b(0[689]\d{8})\b`),
+		expand: func(m []string) string {
+			var groups []string
+			for _, g := range m[1:] {
+				if g != "" {
+					groups = append(groups, readDigits(g))
+				}
+			}
+			return strings.Join(groups, " ")
+		},
+	},
+	{
+		// วันที่ วัน/เดือน/ปี เช่น 25/12/2567 (พ.ศ.) หรือ 25-12-2024 (ค.ศ.)
+		name:    "date",
+		pattern: regexp.MustCompile(`\b(\d{1,2})[/-](\d{1,2})[/-](\d{4})\b`),
+		expand: func(m []string) string {
+			day, _ := strconv.Atoi(m[1])
+			month, _ := strconv.Atoi(m[2])
+			if day < 1 || day > 31 || month < 1 || month > 12 {
+				return ""
+			}
+			return thaiNumber(m[1]) + " " + thaiMonthNames[month-1] + " " + thaiEra(m[3]) + thaiNumber(m[3])
+		},
+	},
+	{
+		// ศักราชที่เขียนย่อ เช่น พ.ศ. 2567, ค.ศ. 2024
+		name:    "era",
+		pattern: regexp.MustCompile(`(พ\.ศ\.|ค\.ศ\.)\s*(\d{1,4})\b`),
+		expand: func(m []string) string {
+			era := "พุทธศักราช"
+			if m[1] == "ค.ศ." {
+				era = "คริสต์ศักราช"
+			}
+			return era + " " + thaiNumber(m[2])
+		},
+	},
+	{
+		// วันที่พร้อมชื่อเดือนย่อ เช่น 25 ธ.ค. 67 (รวมช่องว่างหลังชื่อเดือนเป็นช่องเดียว)
+		name:    "month",
+		pattern: regexp.MustCompile(`\b(\d{1,2})\s*(` + alternation(thaiMonthAbbreviations) + `)\s*`),
+		expand: func(m []string) string {
+			return thaiNumber(m[1]) + " " + thaiMonthAbbreviations[m[2]] + " "
+		},
+	},
+	{
+		// เวลา เช่น 14:30 น., 14.30 น., 08:05:30
+		// คำว่า น. ถูกแทนด้วยช่องว่างหนึ่งช่อง (รวมช่องว่างที่ตามมา)
+		name:    "time",
+		pattern: regexp.MustCompile(`\b([01]?\d|2[0-3])[:.]([0-5]\d)(?:[:.]([0-5]\d))?\s*(?:น\.|นาฬิกา)\s*|\b([01]?\d|2[0-3]):([0-5]\d)(?::([0-5]\d))?\b`),
+		expand: func(m []string) string {
+			hour, minute, second, suffix := m[1], m[2], m[3], " "
+			if hour == "" {
+				hour, minute, second, suffix = m[4], m[5], m[6], ""
+			}
+			words := thaiNumber(hour) + "นาฬิกา"
+			if strings.Trim(minute, "0") != "" {
+				words += " " + thaiNumber(minute) + "นาที"
+			}
+			if strings.Trim(second, "0") != "" {
+				words += " " + thaiNumber(second) + "วินาที"
+			}
+			return words + suffix
+		},
+	},
+	{
+		// สกุลเงินหน้าตัวเลข เช่น ฿500, $20
+		name:    "currency",
+		pattern: regexp.MustCompile(`(` + alternation(thaiCurrencyWords) + `)\s*(` + numberPattern + `)`),
+		expand: func(m []string) string {
+			return readNumber(m[2]) + thaiCurrencyWords[m[1]]
+		},
+	},
+	{
+		// ลำดับที่แบบอังกฤษ เช่น 1st, 22nd, 3rd, 4th
+		name:    "ordinal",
+		pattern: regexp.MustCompile(`\b(\d+)(?:st|nd|rd|th)\b`),
+		expand: func(m []string) string {
+			return "ที่" + thaiNumber(m[1])
+		},
+	},
+	{
+		// ตัวเลขพร้อมหน่วย เช่น 3.5 กม., 15%, 30°C
+		name:    "unit",
+		pattern: regexp.MustCompile(`(` + numberPattern + `)\s*(` + alternation(thaiUnitWords) + `)([^A-Za-z]|$)`),
+		expand: func(m []string) string {
+			return readNumber(m[1]) + thaiUnitWords[m[2]] + m[3]
+		},
+	},
+	{
+		// ตัวเลขทั่วไป จำนวนเต็ม มีจุลภาค หรือทศนิยม
+		name:    "number",
+		pattern: regexp.MustCompile(numberPattern),
+		expand: func(m []string) string {
+			return readNumber(m[0])
+		},
+	},
+}
+
+// แปลงตัวเลข วันที่ เวลา สกุลเงิน และหน่วยเป็นคำอ่านภาษาไทย
+func normalizeThaiText(text string) string {
+	text = thaiDigitReplacer.Replace(text)
+	for _, rule := range thaiNormalizeRules {
+		text = replaceAllSubmatchFunc(rule.pattern, text, rule.expand)
+	}
+	return text
+}
+
+// เลขไทยเป็นเลขอารบิก
+var thaiDigitReplacer = strings.NewReplacer(
+	"๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4",
+	"๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9",
+)
+
+// แทนที่ทุกตำแหน่งที่ตรงกับรูปแบบ โดยส่งกลุ่มย่อยให้ฟังก์ชัน (คืน "" = คงข้อความเดิม)
+func replaceAllSubmatchFunc(re *regexp.Regexp, text string, fn func([]string) string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for _, loc := range matches {
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		replacement := fn(groups)
+		if replacement == "" {
+			replacement = groups[0]
+		}
+		sb.WriteString(text[last:loc[0]])
+		sb.WriteString(replacement)
+		last = loc[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// สร้าง alternation ของ regexp จาก key ของตาราง (ยาวก่อนสั้น เพื่อให้ตรงแบบยาวที่สุด)
+func alternation(table map[string]string) string {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	for i, k := range keys {
+		keys[i] = regexp.QuoteMeta(k)
+	}
+	return strings.Join(keys, "|")
+}
+
+// คำนำหน้าศักราชของปีในวันที่ (ปีตั้งแต่ 2400 ถือเป็น พ.ศ.)
+func thaiEra(year string) string {
+	y, _ := strconv.Atoi(year)
+	if y >= 2400 {
+		return "พุทธศักราช "
+	}
+	return "คริสต์ศักราช "
+}
+
+// อ่านตัวเลขที่อาจมีจุลภาคหรือทศนิยม เช่น "12,500" หรือ "3.14"
+func readNumber(s string) string {
+	s = strings.ReplaceAll(s, ",", "")
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+	words := ""
+	if len(integer) > 1 && integer[0] == '0' {
+		// ขึ้นต้นด้วย 0 เช่น รหัส 007 อ่านทีละตัว
+		words = readDigits(integer)
+	} else {
+		words = thaiNumber(integer)
+	}
+	if hasFraction {
+		words += "จุด" + readDigits(fraction)
+	}
+	return words
+}
+
+// อ่านตัวเลขทีละตัว
+func readDigits(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			sb.WriteString(thaiDigitWords[c-'0'])
+		}
+	}
+	return sb.String()
+}
+
+// อ่านจำนวนเต็มเป็นภาษาไทย เช่น "12500" เป็น "หนึ่งหมื่นสองพันห้าร้อย"
+func thaiNumber(digits string) string {
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return thaiDigitWords[0]
+	}
+	if len(digits) > 6 {
+		high, low := digits[:len(digits)-6], digits[len(digits)-6:]
+		return thaiNumber(high) + "ล้าน" + thaiBelowMillion(low, true)
+	}
+	return thaiBelowMillion(digits, false)
+}
+
+// อ่านตัวเลขไม่เกินหกหลัก (hasHigher = มีหลักล้านอยู่ข้างหน้า)
+func thaiBelowMillion(digits string, hasHigher bool) string {
+	var sb strings.Builder
+	n := len(digits)
+	for i, c := range digits {
+		d := int(c - '0')
+		place := n - 1 - i
+		if d == 0 {
+			continue
+		}
+		switch {
+		case place == 1 && d == 1:
+			sb.WriteString("สิบ")
+		case place == 1 && d == 2:
+			sb.WriteString("ยี่สิบ")
+		case place == 0 && d == 1 && (hasHigher || sb.Len() > 0):
+			sb.WriteString("เอ็ด")
+		default:
+			sb.WriteString(thaiDigitWords[d] + thaiPlaceWords[place])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestNormalizeThaiText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"comma number", "12,500 บาท", "หนึ่งหมื่นสองพันห้าร้อย บาท"},
+		{"decimal with comma", "ราคา 1,250.75 บาท", "ราคา หนึ่งพันสองร้อยห้าสิบจุดเจ็ดห้า บาท"},
+		{"buddhist date", "25/12/2567", "ยี่สิบห้า ธันวาคม พุทธศักราช สองพันห้าร้อยหกสิบเจ็ด"},
+		{"christian date", "1-5-2024", "หนึ่ง พฤษภาคม คริสต์ศักราช สองพันยี่สิบสี่"},
+		{"invalid date", "5/13/2024", "ห้า/สิบสาม/สองพันยี่สิบสี่"},
+		{"time", "เริ่ม 14:30 น. ตรง", "เริ่ม สิบสี่นาฬิกา สามสิบนาที ตรง"},
+		{"time with dot", "14.30 น.", "สิบสี่นาฬิกา สามสิบนาที "},
+		{"time on the hour", "เวลา 9:00 นาฬิกา", "เวลา เก้านาฬิกา "},
+		{"time with seconds", "08:05:30", "แปดนาฬิกา ห้านาที สามสิบวินาที"},
+		{"distance", "3.5 กม.", "สามจุดห้ากิโลเมตร"},
+		{"speed", "ขับ 90 กม./ชม. ต่อไป", "ขับ เก้าสิบกิโลเมตรต่อชั่วโมง ต่อไป"},
+		{"temperature", "30°C", "สามสิบองศาเซลเซียส"},
+		{"christian era", "ค.ศ. 2024", "คริสต์ศักราช สองพันยี่สิบสี่"},
+		{"buddhist era", "พ.ศ.2567", "พุทธศักราช สองพันห้าร้อยหกสิบเจ็ด"},
+		{"month abbreviation", "25 ธ.ค. 67", "ยี่สิบห้า ธันวาคม หกสิบเจ็ด"},
+		{"mobile phone", "โทร 081-234-5678", "โทร ศูนย์แปดหนึ่ง สองสามสี่ ห้าหกเจ็ดแปด"},
+		{"landline", "02-123-4567", "ศูนย์สอง หนึ่งสองสาม สี่ห้าหกเจ็ด"},
+		{"phone without dashes", "0812345678", "ศูนย์แปดหนึ่งสองสามสี่ห้าหกเจ็ดแปด"},
+		{"percent", "15%", "สิบห้าเปอร์เซ็นต์"},
+		{"decimal percent", "ลดลง 2.5% แล้ว", "ลดลง สองจุดห้าเปอร์เซ็นต์ แล้ว"},
+		{"currency", "฿500", "ห้าร้อยบาท"},
+		{"dollar", "$1,000", "หนึ่งพันดอลลาร์"},
+		{"ordinal 1st", "1st", "ที่หนึ่ง"},
+		{"ordinal 22nd", "22nd", "ที่ยี่สิบสอง"},
+		{"ordinal 3rd", "3rd", "ที่สาม"},
+		{"ordinal 11th", "11th", "ที่สิบเอ็ด"},
+		{"ed 11", "11", "สิบเอ็ด"},
+		{"ed 21", "21", "ยี่สิบเอ็ด"},
+		{"ed 101", "101", "หนึ่งร้อยเอ็ด"},
+		{"ed 111", "111", "หนึ่งร้อยสิบเอ็ด"},
+		{"ed 2021", "2,021", "สองพันยี่สิบเอ็ด"},
+		{"ed million", "1,000,001", "หนึ่งล้านเอ็ด"},
+		{"one", "1", "หนึ่ง"},
+		{"ten", "10", "สิบ"},
+		{"twenty", "20", "ยี่สิบ"},
+		{"million", "1000000", "หนึ่งล้าน"},
+		{"ten million", "10,000,000", "สิบล้าน"},
+		{"zero", "0", "ศูนย์"},
+		{"decimal below one", "0.5", "ศูนย์จุดห้า"},
+		{"leading zero", "เลข 007", "เลข ศูนย์ศูนย์เจ็ด"},
+		{"thai digits", "๑๒๓", "หนึ่งร้อยยี่สิบสาม"},
+		{"no numbers", "ไม่มีตัวเลข", "ไม่มีตัวเลข"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeThaiText(tt.in); got != tt.want {
+				t.Errorf("normalizeThaiText(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
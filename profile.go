@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// รูปแบบไฟล์เสียง output: codec, bitrate, sample rate และจำนวนช่องเสียง
+type outputProfile struct {
+	Name       string
+	Extension  string   // นามสกุลไฟล์ output
+	Codec      string   // encoder ของ ffmpeg
+	Bitrate    string   // ว่าง = lossless หรือใช้ bitrate จาก config (โปรไฟล์ mp3)
+	SampleRate int      // Hz
+	Channels   int      // 1 = mono, 2 = stereo
+	Args       []string // argument เพิ่มเติมของ encoder
+	// AudioEncoding ที่ขอจาก Cloud TTS เพื่อไม่ต้องแปลงจาก MP3 ที่เสียคุณภาพไปแล้ว
+	CloudEncoding string
+}
+
+// โปรไฟล์เริ่มต้น: MP3 stereo 48kHz ตาม bitrate ใน config (รูปแบบเดิมของ k-tts)
+const defaultOutputProfile = "mp3"
+
+// โปรไฟล์ที่รองรับ
+var outputProfiles = map[string]outputProfile{
+	"mp3": {
+		Extension: "mp3", Codec: "libmp3lame", SampleRate: 48000, Channels: 2,
+		CloudEncoding: "MP3",
+	},
+	"mp3-speech-64k-mono": {
+		Extension: "mp3", Codec: "libmp3lame", Bitrate: "64k", SampleRate: 24000, Channels: 1,
+		CloudEncoding: "MP3",
+	},
+	"opus-32k": {
+		Extension: "opus", Codec: "libopus", Bitrate: "32k", SampleRate: 48000, Channels: 1,
+		Args:          []string{"-application", "voip"},
+		CloudEncoding: "OGG_OPUS",
+	},
+	"aac-he-48k": {
+		Extension: "m4a", Codec: "libfdk_aac", Bitrate: "48k", SampleRate: 48000, Channels: 1,
+		Args:          []string{"-profile:a", "aac_he"},
+		CloudEncoding: "LINEAR16",
+	},
+	"flac": {
+		Extension: "flac", Codec: "flac", SampleRate: 24000, Channels: 1,
+		CloudEncoding: "LINEAR16",
+	},
+	"wav-pcm16": {
+		Extension: "wav", Codec: "pcm_s16le", SampleRate: 24000, Channels: 1,
+		CloudEncoding: "LINEAR16",
+	},
+}
+
+// ชื่อโปรไฟล์ที่รองรับ เรียงตามตัวอักษร
+func outputProfileNames() []string {
+	names := make([]string, 0, len(outputProfiles))
+	for name := range outputProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// โปรไฟล์ที่เลือก
+func (c *Config) outputProfile() outputProfile {
+	profile := outputProfiles[c.Profile]
+	profile.Name = c.Profile
+	if profile.Codec == "libmp3lame" && profile.Bitrate == "" {
+		profile.Bitrate = c.Bitrate
+	}
+	return profile
+}
+
+// argument ของ ffmpeg สำหรับเข้ารหัสเสียงตามโปรไฟล์
+func (p outputProfile) encodeArgs() []string {
+	args := []string{"-c:a", p.Codec}
+	if p.Bitrate != "" {
+		args = append(args, "-b:a", p.Bitrate)
+	}
+	args = append(args, p.Args...)
+	return append(args,
+		"-ar", strconv.Itoa(p.SampleRate),
+		"-ac", strconv.Itoa(p.Channels))
+}
+
+// ไฟล์ MP3 ใส่ tag ด้วย ID3 writer ของเราเอง รูปแบบอื่นใช้ -metadata ของ ffmpeg
+func (p outputProfile) usesID3() bool {
+	return p.Extension == "mp3"
+}
+
+// นามสกุลไฟล์เสียงที่ Cloud TTS ส่งกลับตาม AudioEncoding (LINEAR16 มี WAV header)
+func cloudEncodingFormat(encoding string) string {
+	switch encoding {
+	case "OGG_OPUS":
+		return "ogg"
+	case "LINEAR16":
+		return "wav"
+	}
+	return "mp3"
+}
+
+// รายการ encoder ของ ffmpeg (แทนที่ได้ในการทดสอบ)
+var listFFmpegEncoders = func() ([]byte, error) {
+	return exec.Command("ffmpeg", "-hide_banner", "-encoders").Output()
+}
+
+// ตรวจสอบว่า ffmpeg มี encoder ที่โปรไฟล์ต้องใช้
+func checkEncoder(codec string) error {
+	output, err := listFFmpegEncoders()
+	if err != nil {
+		return fmt.Errorf("ไม่สามารถเรียก ffmpeg: %v", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == codec {
+			return nil
+		}
+	}
+	return fmt.Errorf("ffmpeg ไม่มี encoder %s", codec)
+}
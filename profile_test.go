@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+)
+
+func TestOutputProfiles(t *testing.T) {
+	tests := []struct {
+		profile string
+		args    []string
+		format  string // ไฟล์ที่ Cloud TTS ส่งกลับ
+		id3     bool
+	}{
+		{"mp3", []string{"-c:a", "libmp3lame", "-b:a", "128k", "-ar", "48000", "-ac", "2"}, "mp3", true},
+		{"mp3-speech-64k-mono", []string{"-c:a", "libmp3lame", "-b:a", "64k", "-ar", "24000", "-ac", "1"}, "mp3", true},
+		{"opus-32k", []string{"-c:a", "libopus", "-b:a", "32k", "-application", "voip", "-ar", "48000", "-ac", "1"}, "ogg", false},
+		{"aac-he-48k", []string{"-c:a", "libfdk_aac", "-b:a", "48k", "-profile:a", "aac_he", "-ar", "48000", "-ac", "1"}, "wav", false},
+		{"flac", []string{"-c:a", "flac", "-ar", "24000", "-ac", "1"}, "wav", false},
+		{"wav-pcm16", []string{"-c:a", "pcm_s16le", "-ar", "24000", "-ac", "1"}, "wav", false},
+	}
+	if len(tests) != len(outputProfiles) {
+		t.Errorf("test covers %d profiles, want all %d: %v", len(tests), len(outputProfiles), outputProfileNames())
+	}
+
+	cfg := defaultConfig()
+	cfg.Bitrate = "128k"
+	for _, tt := range tests {
+		cfg.Profile = tt.profile
+		profile := cfg.outputProfile()
+		if profile.Name != tt.profile {
+			t.Errorf("%s: Name = %q", tt.profile, profile.Name)
+		}
+		if got := profile.encodeArgs(); !reflect.DeepEqual(got, tt.args) {
+			t.Errorf("%s: encodeArgs = %q, want %q", tt.profile, got, tt.args)
+		}
+		if _, ok := texttospeechpb.AudioEncoding_value[profile.CloudEncoding]; !ok {
+			t.Errorf("%s: CloudEncoding %q is not a Cloud TTS AudioEncoding", tt.profile, profile.CloudEncoding)
+		}
+		if got := cloudEncodingFormat(profile.CloudEncoding); got != tt.format {
+			t.Errorf("%s: cloudEncodingFormat(%s) = %q, want %q", tt.profile, profile.CloudEncoding, got, tt.format)
+		}
+		if got := profile.usesID3(); got != tt.id3 {
+			t.Errorf("%s: usesID3 = %v, want %v", tt.profile, got, tt.id3)
+		}
+	}
+}
+
+// รายการ encoder ของ ffmpeg build ที่ไม่มี libfdk_aac
+const testEncoderListing = `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ A....D aac                  AAC (Advanced Audio Coding)
+ A..... libmp3lame           libmp3lame MP3 (MPEG audio layer 3) (codec mp3)
+ A..... libopus              libopus Opus (codec opus)
+ A..... flac                 FLAC (Free Lossless Audio Codec)
+ A..... pcm_s16le            PCM signed 16-bit little-endian
+`
+
+// แทนที่รายการ encoder ของ ffmpeg ระหว่างการทดสอบ
+func useTestEncoders(t *testing.T, listing string, err error) {
+	t.Helper()
+	original := listFFmpegEncoders
+	listFFmpegEncoders = func() ([]byte, error) { return []byte(listing), err }
+	t.Cleanup(func() { listFFmpegEncoders = original })
+}
+
+func TestCheckEncoder(t *testing.T) {
+	useTestEncoders(t, testEncoderListing, nil)
+	for _, name := range outputProfileNames() {
+		codec := outputProfiles[name].Codec
+		err := checkEncoder(codec)
+		if name == "aac-he-48k" {
+			if err == nil || !strings.Contains(err.Error(), "libfdk_aac") {
+				t.Errorf("checkEncoder(%s) = %v, want missing libfdk_aac", codec, err)
+			}
+		} else if err != nil {
+			t.Errorf("checkEncoder(%s): %v", codec, err)
+		}
+	}
+	// ชื่อในคำอธิบายไม่นับเป็น encoder
+	if err := checkEncoder("mp3"); err == nil {
+		t.Error("checkEncoder(mp3) matched a description, want error")
+	}
+
+	useTestEncoders(t, "", fmt.Errorf("exec: \"ffmpeg\": executable file not found"))
+	if err := checkEncoder("libmp3lame"); err == nil {
+		t.Error("checkEncoder without ffmpeg succeeded, want error")
+	}
+}
+
+func TestRunBatchRejectsMissingEncoder(t *testing.T) {
+	useTestEncoders(t, testEncoderListing, nil)
+
+	input := t.TempDir()
+	if err := os.WriteFile(filepath.Join(input, "001.txt"), []byte("สวัสดีครับ"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultConfig()
+	cfg.InputDir = input
+	cfg.OutputDir = t.TempDir()
+	cfg.CacheDir = t.TempDir()
+	cfg.Profile = "aac-he-48k"
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if code := runBatch(context.Background(), &cfg); code != 1 {
+		t.Errorf("runBatch = %d, want 1", code)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// อัตราต่ำสุดเมื่อลดอัตราซ้ำๆ (สัดส่วนของอัตราที่ตั้งไว้)
+const rateLimiterMinFraction = 1.0 / 16
+
+// สัดส่วนอัตราที่เพิ่มคืนต่อคำขอที่สำเร็จหลังถูกลดอัตรา
+const rateLimiterRecoveryStep = 0.05
+
+// ไม่ลดอัตราซ้ำภายในช่วงนี้ (worker หลายตัวมักได้ 429 พร้อมกัน)
+const rateLimiterThrottleCooldown = 2 * time.Second
+
+// token bucket ที่ใช้ร่วมกันทุก worker ของ engine เดียวกัน
+// ลดอัตราลงครึ่งหนึ่งเมื่อถูกจำกัด (429) แล้วค่อยๆ เพิ่มคืนเมื่อคำขอสำเร็จ
+type rateLimiter struct {
+	name    string
+	maxRate float64 // คำขอต่อวินาทีที่ตั้งไว้
+	burst   float64
+
+	mu          sync.Mutex
+	rate        float64 // อัตราปัจจุบัน (ลดลงหลังถูกจำกัด)
+	tokens      float64 // ติดลบได้ = มีคำขอจองคิวรออยู่
+	last        time.Time
+	throttledAt time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// สร้าง limiter (rate <= 0 = ไม่จำกัด คืนค่า nil ซึ่งใช้งานได้ทุก method)
+func newRateLimiter(name string, rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		name:    name,
+		maxRate: rate,
+		burst:   float64(burst),
+		rate:    rate,
+		tokens:  float64(burst),
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// เติม token ตามเวลาที่ผ่านไป
+func (l *rateLimiter) refillLocked(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// รอจนได้สิทธิ์ส่งคำขอหนึ่งครั้ง หรือจนกว่า ctx จะถูกยกเลิก
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	// จองคิวทันทีเพื่อให้ worker ได้สิทธิ์ตามลำดับที่ร้องขอ
+	l.mu.Lock()
+	l.refillLocked(l.now())
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	err := l.sleep(ctx, wait)
+	if err != nil {
+		// คืนคิวที่จองไว้
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// ลดอัตราลงครึ่งหนึ่งเมื่อ server แจ้งว่าส่งคำขอถี่เกินไป
+func (l *rateLimiter) Throttle() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.throttledAt) < rateLimiterThrottleCooldown {
+		return
+	}
+	l.refillLocked(now)
+	l.throttledAt = now
+	l.rate /= 2
+	if min := l.maxRate * rateLimiterMinFraction; l.rate < min {
+		l.rate = min
+	}
+	if l.tokens > 0 {
+		l.tokens = 0
+	}
+	fmt.Printf("🐢 %s: ลดอัตราเหลือ %.2f คำขอ/วินาที\n", l.name, l.rate)
+}
+
+// เพิ่มอัตราคืนทีละน้อยหลังคำขอสำเร็จ
+func (l *rateLimiter) Success() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate >= l.maxRate {
+		return
+	}
+	now := l.now()
+	if now.Sub(l.throttledAt) < rateLimiterThrottleCooldown {
+		return
+	}
+	l.refillLocked(now)
+	l.rate += l.maxRate * rateLimiterRecoveryStep
+	if l.rate >= l.maxRate {
+		l.rate = l.maxRate
+		fmt.Printf("🐇 %s: กลับสู่อัตราปกติ %.2f คำขอ/วินาที\n", l.name, l.rate)
+	}
+}
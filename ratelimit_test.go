@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// นาฬิกาสมมติ: การรอเลื่อนเวลาไปทันทีและบันทึกเวลาที่รอ
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// limiter ที่ใช้นาฬิกาสมมติ
+func newTestRateLimiter(rate float64, burst int) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	l := newRateLimiter("test", rate, burst)
+	l.now = func() time.Time { return clock.now }
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		clock.waits = append(clock.waits, d)
+		clock.advance(d)
+		return nil
+	}
+	return l, clock
+}
+
+// เรียก Wait n ครั้งแล้วคืนเวลาที่ต้องรอของแต่ละครั้ง
+func waitN(t *testing.T, l *rateLimiter, clock *fakeClock, n int) []time.Duration {
+	t.Helper()
+	clock.waits = nil
+	var waits []time.Duration
+	for i := 0; i < n; i++ {
+		before := len(clock.waits)
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		wait := time.Duration(0)
+		if len(clock.waits) > before {
+			wait = clock.waits[before]
+		}
+		waits = append(waits, wait)
+	}
+	return waits
+}
+
+func approxRate(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestRateLimiterBurst(t *testing.T) {
+	l, clock := newTestRateLimiter(2, 3)
+
+	// burst 3 คำขอแรกไม่ต้องรอ จากนั้นรอตามอัตรา 2 คำขอ/วินาที
+	want := []time.Duration{0, 0, 0, 500 * time.Millisecond, 500 * time.Millisecond}
+	if got := waitN(t, l, clock, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l, clock := newTestRateLimiter(2, 3)
+	waitN(t, l, clock, 3)
+
+	// 1 วินาทีเติมได้ 2 token
+	clock.advance(time.Second)
+	want := []time.Duration{0, 0, 500 * time.Millisecond}
+	if got := waitN(t, l, clock, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits after 1s = %v, want %v", got, want)
+	}
+
+	// token เติมได้ไม่เกิน burst แม้ว่างนาน
+	clock.advance(time.Minute)
+	want = []time.Duration{0, 0, 0, 500 * time.Millisecond}
+	if got := waitN(t, l, clock, 4); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits after 1m = %v, want %v", got, want)
+	}
+}
+
+func TestRateLimiterThrottle(t *testing.T) {
+	l, clock := newTestRateLimiter(4, 4)
+
+	// ลดอัตราครึ่งหนึ่งและยกเลิก token ที่สะสมไว้
+	l.Throttle()
+	if l.rate != 2 {
+		t.Errorf("rate after Throttle = %v, want 2", l.rate)
+	}
+	if got := waitN(t, l, clock, 1); got[0] != 500*time.Millisecond {
+		t.Errorf("wait after Throttle = %v, want 500ms", got[0])
+	}
+
+	// 429 หลายครั้งภายในช่วง cooldown ลดอัตราครั้งเดียว
+	clock.advance(time.Second)
+	l.Throttle()
+	if l.rate != 2 {
+		t.Errorf("rate after Throttle within cooldown = %v, want 2", l.rate)
+	}
+
+	clock.advance(rateLimiterThrottleCooldown)
+	l.Throttle()
+	if l.rate != 1 {
+		t.Errorf("rate after cooldown = %v, want 1", l.rate)
+	}
+
+	// อัตราไม่ต่ำกว่าขั้นต่ำ
+	for i := 0; i < 10; i++ {
+		clock.advance(rateLimiterThrottleCooldown)
+		l.Throttle()
+	}
+	if want := 4 * rateLimiterMinFraction; l.rate != want {
+		t.Errorf("rate after repeated Throttle = %v, want %v", l.rate, want)
+	}
+}
+
+func TestRateLimiterRecovery(t *testing.T) {
+	l, clock := newTestRateLimiter(4, 1)
+	l.Throttle()
+
+	// ยังไม่เพิ่มคืนภายในช่วง cooldown
+	l.Success()
+	if l.rate != 2 {
+		t.Errorf("rate after Success within cooldown = %v, want 2", l.rate)
+	}
+
+	// เพิ่มคืนครั้งละ 5% ของอัตราที่ตั้งไว้
+	clock.advance(rateLimiterThrottleCooldown)
+	for i, want := range []float64{2.2, 2.4, 2.6} {
+		l.Success()
+		if !approxRate(l.rate, want) {
+			t.Errorf("rate after Success %d = %v, want %v", i+1, l.rate, want)
+		}
+	}
+
+	// ไม่เกินอัตราที่ตั้งไว้
+	for i := 0; i < 20; i++ {
+		l.Success()
+	}
+	if l.rate != 4 {
+		t.Errorf("rate after recovery = %v, want 4", l.rate)
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	l, clock := newTestRateLimiter(1, 1)
+	waitN(t, l, clock, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Fatal("Wait with a canceled context succeeded, want error")
+	}
+	// คิวที่จองไว้ถูกคืน คำขอถัดไปจึงรอเพียงหนึ่งช่วง
+	if got := waitN(t, l, clock, 1); got[0] != time.Second {
+		t.Errorf("wait after cancel = %v, want 1s", got[0])
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	l := newRateLimiter("test", 0, 5)
+	if l != nil {
+		t.Fatalf("newRateLimiter(0) = %+v, want nil", l)
+	}
+	l.Throttle()
+	l.Success()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("nil Wait = %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// นโยบายการลองใหม่แบบ exponential backoff พร้อม jitter
+type RetryPolicy struct {
+	MaxAttempts int           // จำนวนครั้งทั้งหมดรวมครั้งแรก
+	BaseDelay   time.Duration // เวลารอก่อนลองใหม่ครั้งแรก
+	MaxDelay    time.Duration // เวลารอสูงสุดต่อครั้ง
+	Jitter      float64       // สัดส่วนการสุ่ม 0 - 1 (0.5 = รอ 50% - 100% ของค่าที่คำนวณได้)
+
+	// ฟังก์ชันสุ่มและรอ (แทนที่ได้ในการทดสอบ)
+	random func() float64
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// ข้อผิดพลาดที่ควรลองใหม่ พร้อมเวลารอที่ server ร้องขอ (Retry-After)
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// ทำเครื่องหมายว่าข้อผิดพลาดนี้ลองใหม่ได้
+func retryable(err error, retryAfter time.Duration) error {
+	return &retryableError{err: err, retryAfter: retryAfter}
+}
+
+// เวลารอก่อนลองครั้งที่ attempt+1 (attempt เริ่มที่ 1)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	random := p.random
+	if random == nil {
+		random = rand.Float64
+	}
+	jitter := p.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 - jitter*random()))
+	}
+	return delay
+}
+
+// เรียก fn ซ้ำจนสำเร็จ, ได้ข้อผิดพลาดที่ลองใหม่ไม่ได้, ครบจำนวนครั้ง หรือ ctx ถูกยกเลิก
+func (p RetryPolicy) do(ctx context.Context, fn func(attempt int) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	sleep := p.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn(attempt)
+		if err == nil {
+			return nil
+		}
+
+		retryErr, ok := err.(*retryableError)
+		if !ok || attempt == attempts {
+			break
+		}
+
+		// Retry-After ที่นานกว่าเวลารอสูงสุดไม่ลองใหม่ก่อนเวลา เพราะ server จะปฏิเสธอีก
+		if p.MaxDelay > 0 && retryErr.retryAfter > p.MaxDelay {
+			return fmt.Errorf("server ขอให้รอ %v ซึ่งนานกว่า retry_max_delay (%v): %v", retryErr.retryAfter, p.MaxDelay, retryErr.err)
+		}
+		delay := p.backoff(attempt)
+		if retryErr.retryAfter > delay {
+			delay = retryErr.retryAfter
+		}
+		fmt.Printf("🔁 ลองใหม่ครั้งที่ %d/%d ในอีก %.1f วินาที: %s\n", attempt+1, attempts, delay.Seconds(), err.Error())
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return sleepErr
+		}
+	}
+
+	if retryErr, ok := err.(*retryableError); ok {
+		return fmt.Errorf("ล้มเหลวหลังลอง %d ครั้ง: %v", attempts, retryErr.err)
+	}
+	return err
+}
+
+// รอตามเวลาที่กำหนด หรือจนกว่า ctx จะถูกยกเลิก
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// อ่าน header Retry-After (จำนวนวินาที หรือวันเวลาแบบ HTTP)
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// status code ที่ควรลองใหม่
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// เสียงปลอมที่ยาวพอให้ fetch ถือว่าเป็นเสียงจริง
+var fakeMP3 = bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 512)
+
+// server ที่ตอบตามลำดับ status ที่กำหนด (ครั้งสุดท้ายซ้ำเมื่อเกินจำนวน)
+type scriptedServer struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	requests int
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.statuses[min(s.requests, len(s.statuses)-1)]
+	s.requests++
+	s.mu.Unlock()
+
+	if status != http.StatusOK {
+		for key, values := range s.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		return
+	}
+	w.Write(fakeMP3)
+}
+
+// Translate TTS ที่ชี้ไปยัง server ทดสอบ และบันทึกเวลารอแทนการรอจริง
+func newTestTranslateTTS(t *testing.T, handler http.Handler, attempts int) (*translateTTS, *[]time.Duration) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := defaultConfig()
+	engine := newTranslateTTS(&cfg)
+	engine.endpoint = server.URL
+	engine.limiter = nil
+
+	var delays []time.Duration
+	engine.retry = RetryPolicy{
+		MaxAttempts: attempts,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Minute,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			delays = append(delays, d)
+			return nil
+		},
+	}
+	return engine, &delays
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		if got := parseRetryAfter(header, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateRetryAfterSeconds(t *testing.T) {
+	server := &scriptedServer{
+		statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+		header:   http.Header{"Retry-After": {"3"}},
+	}
+	engine, delays := newTestTranslateTTS(t, server, 3)
+
+	_, err := engine.Synthesize(context.Background(), SynthesisRequest{Text: "สวัสดี"})
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if want := []time.Duration{3 * time.Second}; !reflect.DeepEqual(*delays, want) {
+		t.Errorf("delays = %v, want %v", *delays, want)
+	}
+}
+
+func TestTranslateRetryAfterDate(t *testing.T) {
+	server := &scriptedServer{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+		header:   http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}},
+	}
+	engine, delays := newTestTranslateTTS(t, server, 3)
+
+	_, err := engine.Synthesize(context.Background(), SynthesisRequest{Text: "สวัสดี"})
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	// HTTP-date มีความละเอียดระดับวินาที
+	if len(*delays) != 1 || (*delays)[0] < 55*time.Second || (*delays)[0] > time.Minute {
+		t.Errorf("delays = %v, want about 1m", *delays)
+	}
+}
+
+func TestTranslateRetryAfterTooLong(t *testing.T) {
+	server := &scriptedServer{
+		statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+		header:   http.Header{"Retry-After": {"86400"}},
+	}
+	engine, delays := newTestTranslateTTS(t, server, 3)
+
+	_, err := engine.Synthesize(context.Background(), SynthesisRequest{Text: "สวัสดี"})
+	if err == nil {
+		t.Fatal("Synthesize succeeded, want error for Retry-After beyond MaxDelay")
+	}
+	if server.requests != 1 || len(*delays) != 0 {
+		t.Errorf("requests = %d, delays = %v, want 1 request and no wait", server.requests, *delays)
+	}
+}
+
+func TestTranslateRetryThenSuccess(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK}}
+	engine, delays := newTestTranslateTTS(t, server, 5)
+
+	chunk, err := engine.Synthesize(context.Background(), SynthesisRequest{Text: "สวัสดี"})
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if !bytes.Equal(chunk.Data, fakeMP3) || chunk.Format != "mp3" {
+		t.Errorf("chunk = %d bytes %q, want fake mp3", len(chunk.Data), chunk.Format)
+	}
+	if server.requests != 3 {
+		t.Errorf("requests = %d, want 3", server.requests)
+	}
+	// ไม่มี Retry-After จึงรอตาม backoff (ไม่มี jitter)
+	if want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}; !reflect.DeepEqual(*delays, want) {
+		t.Errorf("delays = %v, want %v", *delays, want)
+	}
+}
+
+func TestTranslateRetryExhausted(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusServiceUnavailable}}
+	engine, _ := newTestTranslateTTS(t, server, 3)
+
+	_, err := engine.Synthesize(context.Background(), SynthesisRequest{Text: "สวัสดี"})
+	if err == nil {
+		t.Fatal("Synthesize succeeded, want error")
+	}
+	if server.requests != 3 {
+		t.Errorf("requests = %d, want 3", server.requests)
+	}
+}
+
+func TestTranslateNonRetryableStatus(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusBadRequest}}
+	engine, delays := newTestTranslateTTS(t, server, 3)
+
+	_, err := engine.Synthesize(context.Background(), SynthesisRequest{Text: "สวัสดี"})
+	if err == nil {
+		t.Fatal("Synthesize succeeded, want error")
+	}
+	if server.requests != 1 || len(*delays) != 0 {
+		t.Errorf("requests = %d, delays = %v, want 1 request and no retry", server.requests, *delays)
+	}
+}
+
+func TestSynthesizeRequestsAllowPartial(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "bad" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(fakeMP3)
+	})
+	engine, _ := newTestTranslateTTS(t, handler, 2)
+	reqs := []SynthesisRequest{{Text: "หนึ่ง"}, {Text: "bad"}, {Text: "สาม"}, {Text: "bad"}}
+	job := TTSJob{FilePath: "chapter.txt"}
+
+	cfg := defaultConfig()
+	_, _, err := synthesizeRequests(context.Background(), &cfg, nil, engine, job, reqs, t.TempDir())
+	if err == nil {
+		t.Fatal("synthesizeRequests without allow_partial succeeded, want error")
+	}
+
+	cfg.AllowPartial = true
+	dir := t.TempDir()
+	missing, _, err := synthesizeRequests(context.Background(), &cfg, nil, engine, job, reqs, dir)
+	if err != nil {
+		t.Fatalf("synthesizeRequests: %v", err)
+	}
+	if want := []int{2, 4}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %v, want %v", missing, want)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ส่วนย่อยของเอกสาร SSML (tag หรือข้อความ)
+type ssmlToken struct {
+	raw  string
+	name string // ชื่อ tag (ว่างหากเป็นข้อความ)
+	kind ssmlTokenKind
+}
+
+type ssmlTokenKind int
+
+const (
+	ssmlText ssmlTokenKind = iota
+	ssmlOpen
+	ssmlClose
+	ssmlSelfClosing
+)
+
+// element ที่ต้องอยู่ในส่วนเดียวกันทั้งก้อน ห้ามแบ่งข้อความข้างใน
+var ssmlAtomicElements = map[string]bool{
+	"say-as":  true,
+	"sub":     true,
+	"phoneme": true,
+	"audio":   true,
+	"mark":    true,
+}
+
+// แยกเอกสาร SSML เป็น tag และข้อความ (ข้าม comment และ XML declaration)
+func tokenizeSSML(doc string) ([]ssmlToken, error) {
+	var tokens []ssmlToken
+	i := 0
+	for i < len(doc) {
+		if doc[i] != '<' {
+			end := strings.IndexByte(doc[i:], '<')
+			if end < 0 {
+				end = len(doc) - i
+			}
+			tokens = append(tokens, ssmlToken{raw: doc[i : i+end], kind: ssmlText})
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(doc[i:], "<!--") {
+			end := strings.Index(doc[i:], "-->")
+			if end < 0 {
+				return nil, fmt.Errorf("comment ไม่ได้ปิดที่ตำแหน่ง %d", i)
+			}
+			i += end + len("-->")
+			continue
+		}
+
+		// หา '>' ที่ปิด tag โดยข้ามเครื่องหมายที่อยู่ในค่า attribute
+		end := -1
+		var quote byte
+		for j := i + 1; j < len(doc); j++ {
+			c := doc[j]
+			if quote != 0 {
+				if c == quote {
+					quote = 0
+				}
+				continue
+			}
+			if c == '"' || c == '\'' {
+				quote = c
+			} else if c == '>' {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("tag ไม่ได้ปิดที่ตำแหน่ง %d", i)
+		}
+
+		raw := doc[i : end+1]
+		i = end + 1
+		if strings.HasPrefix(raw, "<?") || strings.HasPrefix(raw, "<!") {
+			continue
+		}
+
+		token := ssmlToken{raw: raw, kind: ssmlOpen}
+		inner := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+		if strings.HasPrefix(inner, "/") {
+			token.kind = ssmlClose
+			inner = inner[1:]
+		} else if strings.HasSuffix(inner, "/") {
+			token.kind = ssmlSelfClosing
+			inner = strings.TrimSuffix(inner, "/")
+		}
+		token.name = strings.ToLower(strings.Fields(inner + " ")[0])
+		if token.name == "" {
+			return nil, fmt.Errorf("tag ไม่มีชื่อ: %s", raw)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// ตรวจสอบว่า tag เปิด-ปิดครบ และห่อด้วย <speak> หากยังไม่มี
+func normalizeSSML(doc string) (string, error) {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return "", err
+	}
+
+	var stack []string
+	hasSpeak := false
+	for _, t := range tokens {
+		switch t.kind {
+		case ssmlOpen:
+			if t.name == "speak" {
+				hasSpeak = true
+			}
+			stack = append(stack, t.name)
+		case ssmlClose:
+			if len(stack) == 0 || stack[len(stack)-1] != t.name {
+				return "", fmt.Errorf("tag </%s> ไม่ตรงกับ tag ที่เปิดไว้", t.name)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return "", fmt.Errorf("tag <%s> ไม่ได้ปิด", stack[len(stack)-1])
+	}
+
+	doc = strings.TrimSpace(doc)
+	if !hasSpeak {
+		doc = "<speak>" + doc + "</speak>"
+	}
+	return doc, nil
+}
+
+// แทนที่อักขระพิเศษของ XML ในข้อความ
+func escapeSSML(text string) string {
+	replacer := strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+		"'", "&apos;",
+	)
+	return replacer.Replace(text)
+}
+
+// แปลงข้อความธรรมดา (ย่อหน้าละบรรทัด) เป็น SSML พร้อม <break> ระหว่างย่อหน้าและประโยค
+func textToSSML(text string, pauses documentPauses) string {
+	return textToSSMLWith(text, pauses, escapeSSML)
+}
+
+// แปลงข้อความธรรมดาเป็น SSML โดยใช้ render แปลงข้อความแต่ละย่อหน้าหรือประโยค
+func textToSSMLWith(text string, pauses documentPauses, render func(string) string) string {
+	return parseTextDocument(text).ssml(pauses, render)
+}
+
+// ดึงข้อความที่จะอ่านออกจาก SSML (สำหรับ engine ที่ไม่รองรับ SSML) ย่อหน้าละบรรทัด
+func ssmlToText(doc string) string {
+	return ssmlToDocument(doc).String()
+}
+
+// แยก SSML เป็นย่อหน้า (<p>) และประโยค (<s> หรือเครื่องหมายจบประโยค)
+func ssmlToDocument(doc string) textDocument {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		// เอกสารเสีย: ลบเฉพาะเครื่องหมาย tag ออก
+		return parseTextDocument(strings.NewReplacer("<", " ", ">", " ").Replace(doc))
+	}
+
+	var document textDocument
+	var paragraph textParagraph
+	var sentence strings.Builder
+	endSentence := func() {
+		paragraph.addSentences(sentence.String())
+		sentence.Reset()
+	}
+	endParagraph := func() {
+		endSentence()
+		if len(paragraph.Sentences) > 0 {
+			document.Paragraphs = append(document.Paragraphs, paragraph)
+		}
+		paragraph = textParagraph{}
+	}
+
+	skipDepth := 0
+	for _, t := range tokens {
+		if skipDepth > 0 {
+			switch t.kind {
+			case ssmlOpen:
+				skipDepth++
+			case ssmlClose:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch t.kind {
+		case ssmlText:
+			sentence.WriteString(html.UnescapeString(t.raw))
+		case ssmlOpen:
+			// <sub alias="..."> อ่านตาม alias แทนข้อความข้างใน
+			if t.name == "sub" {
+				if alias, ok := ssmlAttr(t.raw, "alias"); ok {
+					sentence.WriteString(" " + alias + " ")
+					skipDepth = 1
+				}
+			}
+			switch t.name {
+			case "p":
+				endParagraph()
+			case "s":
+				endSentence()
+			}
+		case ssmlClose:
+			switch t.name {
+			case "p":
+				endParagraph()
+			case "s":
+				endSentence()
+			}
+		case ssmlSelfClosing:
+			sentence.WriteString(" ")
+		}
+	}
+	endParagraph()
+	return document
+}
+
+// อ่านค่า attribute จาก tag
+func ssmlAttr(tag, name string) (string, bool) {
+	for _, quote := range []string{`"`, "'"} {
+		key := name + "=" + quote
+		start := strings.Index(tag, key)
+		if start < 0 {
+			continue
+		}
+		start += len(key)
+		end := strings.Index(tag[start:], quote)
+		if end < 0 {
+			return "", false
+		}
+		return html.UnescapeString(tag[start : start+end]), true
+	}
+	return "", false
+}
+
+// หน่วยของเอกสารที่ห้ามแบ่ง: ตัด <speak> ออก และรวม element ที่ห้ามแบ่งเป็น token เดียว
+func ssmlUnits(tokens []ssmlToken) []ssmlToken {
+	var units []ssmlToken
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.name == "speak" {
+			continue
+		}
+		// รวม element ที่ห้ามแบ่งเป็นหน่วยเดียว
+		if t.kind == ssmlOpen && ssmlAtomicElements[t.name] {
+			raw := t.raw
+			depth := 1
+			for i+1 < len(tokens) && depth > 0 {
+				i++
+				next := tokens[i]
+				if next.kind == ssmlOpen {
+					depth++
+				} else if next.kind == ssmlClose {
+					depth--
+				}
+				raw += next.raw
+			}
+			units = append(units, ssmlToken{raw: raw, name: t.name, kind: ssmlSelfClosing})
+			continue
+		}
+		units = append(units, t)
+	}
+	return units
+}
+
+// แบ่งเอกสาร SSML เป็นหลายเอกสารที่ไม่เกิน maxBytes โดยไม่แบ่งกลาง tag
+// tag ที่เปิดค้างไว้จะถูกปิดท้ายส่วนและเปิดใหม่ในส่วนถัดไป
+func splitSSML(doc string, maxBytes int) ([]string, error) {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// ตัด <speak> ออก แล้วห่อใหม่ทุกส่วน
+	units := ssmlUnits(tokens)
+
+	const wrapOpen, wrapClose = "<speak>", "</speak>"
+	var chunks []string
+	var stack []ssmlToken
+	var body strings.Builder
+	hasContent := false
+
+	closingTags := func() string {
+		var sb strings.Builder
+		for i := len(stack) - 1; i >= 0; i-- {
+			sb.WriteString("</" + stack[i].name + ">")
+		}
+		return sb.String()
+	}
+	openingTags := func() string {
+		var sb strings.Builder
+		for _, t := range stack {
+			sb.WriteString(t.raw)
+		}
+		return sb.String()
+	}
+	fits := func(s string) bool {
+		return len(wrapOpen)+body.Len()+len(s)+len(closingTags())+len(wrapClose) <= maxBytes
+	}
+	flush := func() {
+		if hasContent {
+			chunks = append(chunks, wrapOpen+body.String()+closingTags()+wrapClose)
+		}
+		body.Reset()
+		body.WriteString(openingTags())
+		hasContent = false
+	}
+	add := func(s string, content bool) {
+		if !fits(s) && hasContent {
+			flush()
+		}
+		body.WriteString(s)
+		hasContent = hasContent || content
+	}
+
+	for _, u := range units {
+		switch u.kind {
+		case ssmlOpen:
+			// เผื่อที่สำหรับ tag ปิดของตัวเองด้วย
+			if !fits(u.raw+"</"+u.name+">") && hasContent {
+				flush()
+			}
+			body.WriteString(u.raw)
+			stack = append(stack, u)
+		case ssmlClose:
+			body.WriteString(u.raw)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ssmlSelfClosing:
+			// หน่วยที่ห้ามแบ่งต้องใส่ในส่วนใหม่ได้ทั้งก้อน
+			if len(wrapOpen)+len(openingTags())+len(u.raw)+len(closingTags())+len(wrapClose) > maxBytes {
+				return nil, fmt.Errorf("<%s> ยาว %d bytes เกินขีดจำกัด %d bytes ของแต่ละส่วน", u.name, len(u.raw), maxBytes)
+			}
+			add(u.raw, true)
+		case ssmlText:
+			if strings.TrimSpace(u.raw) == "" {
+				body.WriteString(u.raw)
+				continue
+			}
+			// พื้นที่สำหรับข้อความในส่วนใหม่หลังห่อ tag ที่เปิดค้างไว้
+			capacity := maxBytes - len(wrapOpen) - len(wrapClose) - len(openingTags()) - len(closingTags()) - 1
+			if capacity < 1 {
+				return nil, fmt.Errorf("tag ซ้อนกันลึกเกินกว่าจะแบ่งให้ไม่เกิน %d bytes", maxBytes)
+			}
+			if len(u.raw) <= capacity {
+				add(u.raw, true)
+				continue
+			}
+			// ข้อความยาวเกิน: แบ่งตามประโยคจากข้อความที่ถอด entity แล้ว เพื่อไม่ให้ตัดกลาง &amp;
+			text := html.UnescapeString(strings.TrimSpace(u.raw))
+			for i, part := range splitTextSSML(text, capacity) {
+				part = escapeSSML(part)
+				if i > 0 {
+					part = " " + part
+				}
+				add(part, true)
+			}
+		}
+	}
+	flush()
+
+	return chunks, nil
+}
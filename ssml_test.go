@@ -0,0 +1,123 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// entity ที่ถูกต้องใน SSML
+var ssmlEntity = regexp.MustCompile(`^&(amp|lt|gt|quot|apos);`)
+
+// ตรวจสอบว่าทุกส่วนไม่เกินขนาด tag ครบคู่ และไม่มี entity ที่ถูกตัดกลาง
+func checkSSMLChunks(t *testing.T, chunks []string, maxBytes int) {
+	t.Helper()
+	for i, chunk := range chunks {
+		if len(chunk) > maxBytes {
+			t.Errorf("chunk %d is %d bytes, limit %d", i, len(chunk), maxBytes)
+		}
+		if _, err := normalizeSSML(chunk); err != nil {
+			t.Errorf("chunk %d is not balanced: %v\n%s", i, err, chunk)
+		}
+		for j := strings.IndexByte(chunk, '&'); j >= 0; j = strings.IndexByte(chunk, '&') {
+			if !ssmlEntity.MatchString(chunk[j:]) {
+				t.Errorf("chunk %d has a broken entity at %q", i, chunk[j:min(len(chunk), j+8)])
+				break
+			}
+			chunk = chunk[j+1:]
+		}
+	}
+}
+
+// ข้อความที่อ่านจากทุกส่วนรวมกัน (ไม่สนใจช่องว่าง)
+func chunksText(chunks []string) string {
+	var sb strings.Builder
+	for _, chunk := range chunks {
+		sb.WriteString(ssmlToText(chunk))
+	}
+	return strings.Join(strings.Fields(sb.String()), "")
+}
+
+func TestSplitSSMLFits(t *testing.T) {
+	doc := "<speak>" + strings.Repeat("เขาเดินออกไปจากบ้านทันที. ", 20) + "</speak>"
+	if chunks, err := splitSSML(doc, 5000); err != nil || len(chunks) != 1 || chunks[0] != doc {
+		t.Errorf("splitSSML = %q, %v, want the document unchanged", chunks, err)
+	}
+}
+
+func TestSplitSSMLReopensTags(t *testing.T) {
+	sentence := "ศิษย์ของปรมาจารย์ฝึกฝนวิชาดาบทุกวัน. "
+	doc := `<speak><p><prosody rate="slow">` + strings.Repeat(sentence, 10) + `</prosody></p><p>จบ.</p></speak>`
+	chunks, err := splitSSML(doc, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("splitSSML = %d chunks, want several", len(chunks))
+	}
+	checkSSMLChunks(t, chunks, 400)
+
+	// ส่วนที่แบ่งกลาง <prosody> ต้องเปิด tag ใหม่ด้วย attribute เดิม
+	for _, chunk := range chunks[:len(chunks)-1] {
+		if !strings.HasPrefix(chunk, `<speak><p><prosody rate="slow">`) {
+			t.Errorf("chunk does not reopen <prosody>: %s", chunk)
+		}
+	}
+	if got, want := chunksText(chunks), strings.Join(strings.Fields(ssmlToText(doc)), ""); got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestSplitSSMLKeepsEntities(t *testing.T) {
+	// ข้อความยาวที่ไม่มีเครื่องหมายจบประโยค และมี entity ทุกตำแหน่งที่อาจถูกตัด รวมทั้งช่วงที่ไม่มีช่องว่างเลย
+	texts := []string{
+		strings.Repeat("Tom &amp; Jerry &lt;3 &quot;cat&quot; ศิษย์&amp;อาจารย์ ", 30),
+		strings.Repeat("ก&amp;ข&lt;&gt;", 40),
+		strings.Repeat(strings.Repeat("x", 40)+"&amp;", 8),
+	}
+	for _, text := range texts {
+		doc := "<speak>" + text + "</speak>"
+		for _, maxBytes := range []int{60, 61, 62, 63, 64, 97, 150} {
+			chunks, err := splitSSML(doc, maxBytes)
+			if err != nil {
+				t.Fatalf("maxBytes %d: %v", maxBytes, err)
+			}
+			checkSSMLChunks(t, chunks, maxBytes)
+			if got, want := chunksText(chunks), strings.Join(strings.Fields(html.UnescapeString(text)), ""); got != want {
+				t.Errorf("maxBytes %d: text = %q, want %q", maxBytes, got, want)
+			}
+		}
+	}
+}
+
+func TestSplitSSMLKeepsAtomicElements(t *testing.T) {
+	phoneme := `<phoneme alphabet="ipa" ph="tɕʰi">ชี่</phoneme>`
+	doc := "<speak>" + strings.Repeat("พลัง"+phoneme+"ไหลเวียน ", 12) + "</speak>"
+	chunks, err := splitSSML(doc, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSSMLChunks(t, chunks, 200)
+	if got := strings.Count(strings.Join(chunks, ""), phoneme); got != 12 {
+		t.Errorf("chunks contain %d whole <phoneme>, want 12", got)
+	}
+}
+
+func TestSplitSSMLAtomicTooLarge(t *testing.T) {
+	doc := `<speak>ก่อน <say-as interpret-as="characters">` + strings.Repeat("ก", 100) + `</say-as> หลัง</speak>`
+	if chunks, err := splitSSML(doc, 200); err == nil {
+		t.Errorf("splitSSML = %q, want error for <say-as> larger than the limit", chunks)
+	}
+
+	// หน่วยที่ใส่ได้พอดีในส่วนใหม่ไม่ถือเป็นข้อผิดพลาด
+	if _, err := splitSSML(doc, 400); err != nil {
+		t.Errorf("splitSSML(400): %v", err)
+	}
+}
+
+func TestSplitSSMLInvalid(t *testing.T) {
+	if _, err := splitSSML("<speak><p ไม่ปิด", 5000); err == nil {
+		t.Error("splitSSML with an unterminated tag succeeded, want error")
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// รูปแบบไฟล์คำบรรยายที่รองรับ
+var supportedSubtitleFormats = []string{"srt", "vtt", "lrc"}
+
+// ส่วนเสียงหนึ่งส่วนของบท: ประโยคที่อ่าน ความยาวเสียง และช่วงเงียบที่แทรกไว้ภายใน
+type spokenSegment struct {
+	Display   string // ข้อความต้นฉบับของประโยค (ว่าง = แบ่งเวลาให้ Sentences ตามจำนวนตัวอักษร)
+	Sentences []string
+	Duration  time.Duration
+	Silence   time.Duration // ผลรวมของ SSML <break> ในส่วนนี้
+}
+
+// ข้อความต้นฉบับหนึ่งรายการของคำบรรยาย พร้อมจำนวนตัวอักษรที่อ่านของรายการนั้น (ไม่นับช่องว่าง)
+type captionUnit struct {
+	Display string
+	Spoken  int
+}
+
+// คำบรรยายหนึ่งรายการ
+type subtitleCue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// รูปแบบคำบรรยายที่เลือก
+func (c *Config) subtitleFormats() []string {
+	var formats []string
+	for _, format := range strings.Split(c.Subtitles, ",") {
+		if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// ข้อความที่แสดงในคำบรรยายของเนื้อหาบท ตามลำดับเดียวกับข้อความที่ส่งให้ engine
+// บรรทัดต้นฉบับที่ไม่ถูกลบจับคู่กับคำอ่านของบรรทัดนั้นทีละประโยคเมื่อจำนวนประโยคเท่ากัน
+// มิฉะนั้น (เช่น "ค.ศ. 2024" ที่จุดแบ่งประโยคในต้นฉบับแต่ไม่แบ่งในคำอ่าน) แสดงทั้งย่อหน้าเป็นรายการเดียว
+// ไฟล์ SSML ไม่มีต้นฉบับแยกจากคำอ่าน จึงคืน nil
+func captionUnits(cfg *Config, job TTSJob) []captionUnit {
+	if job.SSML {
+		return nil
+	}
+	var units []captionUnit
+	for _, line := range strings.Split(job.Text, "\n") {
+		cleaned, ok := cfg.cleaner.cleanLine(line)
+		if !ok {
+			continue
+		}
+		prepared := prepareCleanedText(cfg, job.Lexicon, cleaned)
+		doc := parseTextDocument(prepared.Text)
+		if prepared.SSML {
+			doc = ssmlToDocument(prepared.Text)
+		}
+		var spoken []string
+		for _, p := range doc.Paragraphs {
+			spoken = append(spoken, p.Sentences...)
+		}
+
+		var source textParagraph
+		source.addSentences(stripEmphasis(line))
+		if len(source.Sentences) == len(spoken) {
+			for i, sentence := range source.Sentences {
+				units = append(units, captionUnit{Display: sentence, Spoken: spokenLength(spoken[i])})
+			}
+			continue
+		}
+		total := 0
+		for _, sentence := range spoken {
+			total += spokenLength(sentence)
+		}
+		units = append(units, captionUnit{Display: source.String(), Spoken: total})
+	}
+	return units
+}
+
+// จำนวนตัวอักษรที่อ่าน ไม่นับช่องว่าง (การแบ่งส่วนและแบ่งภาษาอาจเพิ่มหรือตัดช่องว่าง)
+func spokenLength(text string) int {
+	n := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// แปลงคำบรรยายของคำอ่านเป็นคำบรรยายของข้อความต้นฉบับ
+// เวลาของแต่ละรายการได้จากตำแหน่งตัวอักษรสะสมในคำอ่าน โดยเทียบสัดส่วนภายในประโยคของคำอ่านที่ตำแหน่งนั้นอยู่
+func sourceCues(spoken []subtitleCue, units []captionUnit) []subtitleCue {
+	lengths := make([]int, len(spoken))
+	total := 0
+	for i, cue := range spoken {
+		lengths[i] = spokenLength(cue.Text)
+		total += lengths[i]
+	}
+	unitTotal := 0
+	for _, u := range units {
+		unitTotal += u.Spoken
+	}
+	if total == 0 || unitTotal == 0 {
+		return spoken
+	}
+
+	// เวลาที่ตำแหน่งตัวอักษร offset: ต้นรายการใช้ประโยคถัดไปเมื่ออยู่ที่รอยต่อพอดี ท้ายรายการใช้ประโยคก่อนหน้า
+	timeAt := func(offset int, start bool) time.Duration {
+		pos := 0
+		for i, cue := range spoken {
+			n := lengths[i]
+			if n == 0 {
+				continue
+			}
+			if offset < pos+n || (!start && offset == pos+n) {
+				return cue.Start + (cue.End-cue.Start)*time.Duration(offset-pos)/time.Duration(n)
+			}
+			pos += n
+		}
+		return spoken[len(spoken)-1].End
+	}
+
+	var cues []subtitleCue
+	offset := 0
+	for _, u := range units {
+		if u.Spoken == 0 {
+			continue
+		}
+		// จำนวนตัวอักษรที่ไม่ตรงกัน (เช่น ส่วนที่ขาดเมื่อเปิด allow_partial) ปรับตามสัดส่วน
+		start := offset * total / unitTotal
+		offset += u.Spoken
+		end := offset * total / unitTotal
+		cues = append(cues, subtitleCue{Start: timeAt(start, true), End: timeAt(end, false), Text: u.Display})
+	}
+	return cues
+}
+
+// ประโยคและช่วงเงียบของคำขอหนึ่งส่วน (ช่วงเงียบล้วนไม่มีประโยค)
+func requestSegment(req SynthesisRequest, duration time.Duration) spokenSegment {
+	segment := spokenSegment{Duration: duration}
+	if req.Pause > 0 {
+		return segment
+	}
+	if req.SSML {
+		segment.Silence = ssmlBreakTotal(req.Text)
+	}
+	if req.Display != "" {
+		segment.Display = req.Display
+		return segment
+	}
+	doc := parseTextDocument(req.Text)
+	if req.SSML {
+		doc = ssmlToDocument(req.Text)
+	}
+	for _, p := range doc.Paragraphs {
+		segment.Sentences = append(segment.Sentences, p.Sentences...)
+	}
+	return segment
+}
+
+// ผลรวมความยาวของ <break time="..."> ใน SSML
+func ssmlBreakTotal(doc string) time.Duration {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return 0
+	}
+	var total time.Duration
+	for _, t := range tokens {
+		if t.name != "break" {
+			continue
+		}
+		if value, ok := ssmlAttr(t.raw, "time"); ok {
+			if pause, err := time.ParseDuration(value); err == nil && pause > 0 {
+				total += pause
+			}
+		}
+	}
+	return total
+}
+
+// คำนวณเวลาของแต่ละประโยคจากความยาวเสียงของแต่ละส่วน
+// ส่วนที่มี Display (การประกาศชื่อบท) ใช้เวลาของส่วนนั้นทั้งส่วน (ส่วนที่ติดกันของข้อความเดียวกันรวมเป็นรายการเดียว)
+// ส่วนอื่นแบ่งเวลาพูดให้ประโยคของคำอ่านตามจำนวนตัวอักษร ช่วงเงียบภายในส่วนวางไว้ระหว่างประโยค
+// แล้วแปลงเป็นข้อความต้นฉบับตาม units (nil = แสดงคำอ่าน) และหารด้วย speed ของ atempo ที่ใช้กับไฟล์สุดท้าย
+func segmentCues(segments []spokenSegment, units []captionUnit, speed float64) []subtitleCue {
+	var cues, spoken []subtitleCue
+	var position time.Duration
+	previous := ""
+	for _, segment := range segments {
+		// ช่วงเงียบที่มากผิดปกติ (เช่น engine ไม่รองรับ <break>) ไม่นำมาหัก
+		speech := segment.Duration - segment.Silence
+		if speech < segment.Duration/4 {
+			speech = segment.Duration
+		}
+
+		if segment.Display != "" {
+			if n := len(cues); n > 0 && previous == segment.Display {
+				cues[n-1].End = position + speech
+			} else {
+				cues = append(cues, subtitleCue{Start: position, End: position + speech, Text: segment.Display})
+			}
+			previous = segment.Display
+			position += segment.Duration
+			continue
+		}
+		previous = ""
+
+		runes := 0
+		for _, sentence := range segment.Sentences {
+			runes += utf8.RuneCountInString(sentence)
+		}
+		if runes == 0 {
+			position += segment.Duration
+			continue
+		}
+
+		gap := (segment.Duration - speech) / time.Duration(len(segment.Sentences))
+
+		start := position
+		for _, sentence := range segment.Sentences {
+			length := speech * time.Duration(utf8.RuneCountInString(sentence)) / time.Duration(runes)
+			spoken = append(spoken, subtitleCue{Start: start, End: start + length, Text: sentence})
+			start += length + gap
+		}
+		position += segment.Duration
+	}
+
+	if units != nil {
+		spoken = sourceCues(spoken, units)
+	}
+	cues = append(cues, spoken...)
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+
+	if speed > 0 && speed != 1 {
+		for i := range cues {
+			cues[i].Start = time.Duration(float64(cues[i].Start) / speed)
+			cues[i].End = time.Duration(float64(cues[i].End) / speed)
+		}
+	}
+	return cues
+}
+
+// เวลาในรูปแบบ hh:mm:ss + ตัวคั่น + มิลลิวินาที
+func formatCueTime(d time.Duration, separator string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+// เนื้อหาไฟล์ SubRip (.srt)
+func formatSRT(cues []subtitleCue) string {
+	var sb strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, formatCueTime(cue.Start, ","), formatCueTime(cue.End, ","), cue.Text)
+	}
+	return sb.String()
+}
+
+// เนื้อหาไฟล์ WebVTT (.vtt)
+func formatVTT(cues []subtitleCue) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		text := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(cue.Text)
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", formatCueTime(cue.Start, "."), formatCueTime(cue.End, "."), text)
+	}
+	return sb.String()
+}
+
+// เนื้อหาไฟล์เนื้อเพลง LRC (.lrc) พร้อม tag ชื่อบท ผู้แต่ง และอัลบั้ม
+func formatLRC(cues []subtitleCue, tag id3Tag) string {
+	var sb strings.Builder
+	for _, header := range [][2]string{{"ti", tag.Title}, {"ar", tag.Artist}, {"al", tag.Album}} {
+		if header[1] != "" {
+			fmt.Fprintf(&sb, "[%s:%s]\n", header[0], header[1])
+		}
+	}
+	for _, cue := range cues {
+		cs := cue.Start.Milliseconds() / 10
+		fmt.Fprintf(&sb, "[%02d:%02d.%02d]%s\n", cs/6000, cs/100%60, cs%100, cue.Text)
+	}
+	return sb.String()
+}
+
+// path ของไฟล์คำบรรยายข้างไฟล์เสียง output
+func subtitlePath(outputPath, format string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + format
+}
+
+// เขียนไฟล์คำบรรยายทุกรูปแบบที่เลือกไว้ข้างไฟล์เสียง output
+func writeSubtitles(cfg *Config, job TTSJob, cues []subtitleCue, tag id3Tag) error {
+	for _, format := range cfg.subtitleFormats() {
+		var content string
+		switch format {
+		case "srt":
+			content = formatSRT(cues)
+		case "vtt":
+			content = formatVTT(cues)
+		case "lrc":
+			content = formatLRC(cues, tag)
+		}
+		err := os.WriteFile(subtitlePath(job.OutputPath, format), []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// engine ที่คืนข้อความเป็นส่วนเดียว (ไม่สร้างช่วงเงียบเอง จึงใช้ SSML <break>)
+type passthroughEngine struct{}
+
+func (passthroughEngine) Name() string { return "passthrough" }
+
+func (passthroughEngine) Chunk(in PreparedText) ([]SynthesisRequest, error) {
+	return []SynthesisRequest{{Text: in.Text, SSML: in.SSML}}, nil
+}
+
+func (passthroughEngine) Synthesize(ctx context.Context, req SynthesisRequest) (*AudioChunk, error) {
+	return &AudioChunk{Format: "mp3"}, nil
+}
+
+// การตั้งค่าที่ใช้ชุดกฎ novel (อ่านตัวเลขเป็นคำ และลบบรรทัดหมายเลขบท)
+func testSubtitleConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.SentencePause = "300ms"
+	cfg.ParagraphPause = "700ms"
+	cleaner, err := newTextCleaner("novel", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.cleaner = cleaner
+	return &cfg
+}
+
+const captionText = "ราคา 12,500 บาท. Qi ไหลเวียน\n\n3\nใน ค.ศ. 2024 เขาเกิด."
+
+func TestCaptionUnits(t *testing.T) {
+	cfg := testSubtitleConfig(t)
+	job := TTSJob{Text: captionText, Lexicon: testLexicon(t, "Qi = ชี่\n")}
+
+	// ประโยคต้นฉบับจับคู่กับคำอ่านหลัง normalize และ lexicon บรรทัดหมายเลขบทถูกลบ
+	// "ค.ศ." แบ่งประโยคในต้นฉบับแต่ไม่แบ่งในคำอ่าน จึงแสดงทั้งย่อหน้า
+	want := []captionUnit{
+		{Display: "ราคา 12,500 บาท.", Spoken: spokenLength("ราคา หนึ่งหมื่นสองพันห้าร้อย บาท.")},
+		{Display: "Qi ไหลเวียน", Spoken: spokenLength("ชี่ ไหลเวียน")},
+		{Display: "ใน ค.ศ. 2024 เขาเกิด.", Spoken: spokenLength("ใน คริสต์ศักราช สองพันยี่สิบสี่ เขาเกิด.")},
+	}
+	if got := captionUnits(cfg, job); !reflect.DeepEqual(got, want) {
+		t.Errorf("captionUnits =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := captionUnits(cfg, TTSJob{Text: "<speak>ข้อความ</speak>", SSML: true}); got != nil {
+		t.Errorf("captionUnits of SSML = %+v, want nil", got)
+	}
+}
+
+func TestSourceCues(t *testing.T) {
+	spoken := []subtitleCue{
+		{Start: 0, End: 3 * time.Second, Text: "กขค"},
+		{Start: 4 * time.Second, End: 6 * time.Second, Text: "ง จ"},
+	}
+	tests := []struct {
+		units []captionUnit
+		want  []subtitleCue
+	}{
+		// รายการที่จบที่รอยต่อพอดีไม่รวมช่วงเงียบระหว่างประโยค
+		{[]captionUnit{{"A", 3}, {"B", 2}}, []subtitleCue{
+			{Start: 0, End: 3 * time.Second, Text: "A"},
+			{Start: 4 * time.Second, End: 6 * time.Second, Text: "B"},
+		}},
+		// รายการที่ข้ามประโยคของคำอ่าน
+		{[]captionUnit{{"A", 1}, {"B", 3}, {"C", 1}}, []subtitleCue{
+			{Start: 0, End: time.Second, Text: "A"},
+			{Start: time.Second, End: 5 * time.Second, Text: "B"},
+			{Start: 5 * time.Second, End: 6 * time.Second, Text: "C"},
+		}},
+		// ทั้งย่อหน้าเป็นรายการเดียว
+		{[]captionUnit{{"AB", 5}}, []subtitleCue{{Start: 0, End: 6 * time.Second, Text: "AB"}}},
+	}
+	for _, tt := range tests {
+		if got := sourceCues(spoken, tt.units); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sourceCues(%v) =\n%+v\nwant\n%+v", tt.units, got, tt.want)
+		}
+	}
+}
+
+// คำบรรยายจากส่วนที่ engine แบ่งตามปกติ (แบ่งกลางประโยคได้) ยังแสดงประโยคต้นฉบับตามลำดับ
+func TestSegmentCuesFromChunks(t *testing.T) {
+	cfg := testSubtitleConfig(t)
+	cfg.ChunkSize = 20
+	job := TTSJob{Text: captionText, Lexicon: testLexicon(t, "Qi = ชี่\n")}
+	prepared, err := prepareJobText(cfg, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs, err := newTranslateTTS(cfg).Chunk(prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ความยาวเสียงสมมติ 10ms ต่อตัวอักษร
+	var segments []spokenSegment
+	var total time.Duration
+	for _, req := range reqs {
+		duration := req.Pause
+		if duration == 0 {
+			duration = time.Duration(utf8.RuneCountInString(req.Text)) * 10 * time.Millisecond
+		}
+		segments = append(segments, requestSegment(req, duration))
+		total += duration
+	}
+
+	cues := segmentCues(segments, captionUnits(cfg, job), 1)
+	var texts []string
+	for i, cue := range cues {
+		texts = append(texts, cue.Text)
+		if cue.End <= cue.Start || i > 0 && cue.Start < cues[i-1].End {
+			t.Errorf("cue %d %v-%v overlaps or is empty", i, cue.Start, cue.End)
+		}
+	}
+	if want := []string{"ราคา 12,500 บาท.", "Qi ไหลเวียน", "ใน ค.ศ. 2024 เขาเกิด."}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("cue texts = %q, want %q", texts, want)
+	}
+	if cues[0].Start != 0 || cues[len(cues)-1].End != total {
+		t.Errorf("cues span %v-%v, want 0-%v", cues[0].Start, cues[len(cues)-1].End, total)
+	}
+}
+
+func TestTitleRequestsDisplayTitle(t *testing.T) {
+	cfg := testSubtitleConfig(t)
+	cfg.TitlePause = "1s"
+	job := TTSJob{Title: "บทที่ 12 การเดินทาง", Announcement: "บทที่ สิบสอง การเดินทาง"}
+	reqs, err := titleRequests(cfg, newTranslateTTS(cfg), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SynthesisRequest{
+		{Text: "บทที่ สิบสอง การเดินทาง", Display: "บทที่ 12 การเดินทาง"},
+		{Pause: time.Second},
+	}
+	if !reflect.DeepEqual(reqs, want) {
+		t.Errorf("titleRequests = %+v, want %+v", reqs, want)
+	}
+}
+
+func TestSegmentCuesDisplay(t *testing.T) {
+	segments := []spokenSegment{
+		{Display: "บทที่ 1", Duration: 2 * time.Second},
+		{Duration: time.Second},
+		// ชื่อบทยาวที่ engine แบ่งเป็นสองส่วน ส่วนหลังมี <break> ท้ายชื่อบท
+		{Display: "ประโยคยาว", Duration: time.Second},
+		{Display: "ประโยคยาว", Duration: 2 * time.Second, Silence: 500 * time.Millisecond},
+		{Display: "จบ.", Duration: time.Second},
+	}
+	want := []subtitleCue{
+		{Start: 0, End: 2 * time.Second, Text: "บทที่ 1"},
+		{Start: 3 * time.Second, End: 5500 * time.Millisecond, Text: "ประโยคยาว"},
+		{Start: 6 * time.Second, End: 7 * time.Second, Text: "จบ."},
+	}
+	if got := segmentCues(segments, nil, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("segmentCues =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// ไฟล์สุดท้ายที่เร่งความเร็ว 2 เท่า
+	got := segmentCues(segments, nil, 2)
+	if got[1].Start != 1500*time.Millisecond || got[1].End != 2750*time.Millisecond {
+		t.Errorf("segmentCues at speed 2 = %+v", got[1])
+	}
+}
+
+func TestSegmentCuesProportional(t *testing.T) {
+	segments := []spokenSegment{
+		{Sentences: []string{"กขค", "ง"}, Duration: 4 * time.Second},
+		// ช่วงเงียบ 1 วินาทีวางไว้หลังแต่ละประโยคเท่ากัน
+		{Sentences: []string{"กขค", "ง"}, Duration: 4 * time.Second, Silence: time.Second},
+	}
+	want := []subtitleCue{
+		{Start: 0, End: 3 * time.Second, Text: "กขค"},
+		{Start: 3 * time.Second, End: 4 * time.Second, Text: "ง"},
+		{Start: 4 * time.Second, End: 6250 * time.Millisecond, Text: "กขค"},
+		{Start: 6750 * time.Millisecond, End: 7500 * time.Millisecond, Text: "ง"},
+	}
+	if got := segmentCues(segments, nil, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("segmentCues =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFormatCueTime(t *testing.T) {
+	tests := []struct {
+		d         time.Duration
+		separator string
+		want      string
+	}{
+		{0, ",", "00:00:00,000"},
+		{1500 * time.Millisecond, ",", "00:00:01,500"},
+		{59*time.Minute + 59*time.Second + 999*time.Millisecond, ".", "00:59:59.999"},
+		{time.Hour + 2*time.Minute + 3456*time.Millisecond, ",", "01:02:03,456"},
+		{12*time.Hour + 5*time.Millisecond, ".", "12:00:00.005"},
+	}
+	for _, tt := range tests {
+		if got := formatCueTime(tt.d, tt.separator); got != tt.want {
+			t.Errorf("formatCueTime(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+var formatTestCues = []subtitleCue{
+	{Start: 0, End: 1500 * time.Millisecond, Text: "ราคา 12,500 บาท."},
+	{Start: time.Hour + 2*time.Minute + 3456*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "a < b & c"},
+}
+
+func TestFormatSRT(t *testing.T) {
+	want := "1\n00:00:00,000 --> 00:00:01,500\nราคา 12,500 บาท.\n\n" +
+		"2\n01:02:03,456 --> 01:02:05,000\na < b & c\n\n"
+	if got := formatSRT(formatTestCues); got != want {
+		t.Errorf("formatSRT =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatVTT(t *testing.T) {
+	want := "WEBVTT\n\n" +
+		"00:00:00.000 --> 00:00:01.500\nราคา 12,500 บาท.\n\n" +
+		"01:02:03.456 --> 01:02:05.000\na &lt; b &amp; c\n\n"
+	if got := formatVTT(formatTestCues); got != want {
+		t.Errorf("formatVTT =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatLRC(t *testing.T) {
+	// LRC ไม่มีหลักชั่วโมง นาทีจึงเกิน 60 ได้
+	want := "[ti:บทที่ 1]\n[al:ดาบพิฆาต]\n" +
+		"[00:00.00]ราคา 12,500 บาท.\n" +
+		"[62:03.45]a < b & c\n"
+	got := formatLRC(formatTestCues, id3Tag{Title: "บทที่ 1", Album: "ดาบพิฆาต"})
+	if got != want {
+		t.Errorf("formatLRC =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got, "[ar:") {
+		t.Error("formatLRC wrote an empty artist tag")
+	}
+}
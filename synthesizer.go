@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// คำขอสังเคราะห์เสียงสำหรับข้อความหนึ่งส่วน
+type SynthesisRequest struct {
+	Text  string
+	SSML  bool // Text เป็นเอกสาร SSML
+	Voice VoiceSettings
+	Pause time.Duration // ช่วงเงียบแทนข้อความ (สำหรับ engine ที่เป็น silenceGenerator)
+	// ข้อความต้นฉบับที่แสดงในคำบรรยายของส่วนนี้ (ว่าง = ใช้ประโยคใน Text)
+	// ส่วนที่ติดกันและมี Display เดียวกันเป็นประโยคเดียวกัน
+	Display string
+}
+
+// ข้อความของบทที่พร้อมส่งให้ engine แบ่งเป็นส่วน
+type PreparedText struct {
+	Text string // ข้อความที่ทำความสะอาดแล้ว (ย่อหน้าละบรรทัด) หรือเอกสาร SSML
+	SSML bool
+}
+
+// ผลลัพธ์เสียงของข้อความหนึ่งส่วน
+type AudioChunk struct {
+	Data     []byte
+	Format   string            // นามสกุลไฟล์เสียง เช่น "mp3"
+	Metadata map[string]string // ข้อมูลเพิ่มเติมจาก engine
+}
+
+// Synthesizer คือ TTS engine ที่แปลงข้อความเป็นเสียงทีละส่วน
+type Synthesizer interface {
+	// ชื่อ engine ที่ใช้ใน registry และ fallback chain
+	Name() string
+	// แบ่งข้อความของบทเป็นส่วนที่ engine รับได้ในครั้งเดียว
+	Chunk(in PreparedText) ([]SynthesisRequest, error)
+	// สร้างเสียงจากข้อความหนึ่งส่วน
+	Synthesize(ctx context.Context, req SynthesisRequest) (*AudioChunk, error)
+}
+
+// engine ที่ต้องการปรับปรุงเสียงเพิ่มตอนรวมไฟล์ (filter ของ ffmpeg ที่ใส่ก่อนปรับความเร็ว)
+type audioEnhancer interface {
+	EnhanceFilters(sampleRate int) []string
+}
+
+// engine ที่ไม่รองรับ SSML <break> แต่สร้างช่วงเงียบในรูปแบบเดียวกับเสียงของตัวเองได้
+type silenceGenerator interface {
+	Silence(ctx context.Context, d time.Duration) (*AudioChunk, error)
+}
+
+// engine ที่ตรวจสอบเสียงที่ร้องขอได้ก่อนเริ่มประมวลผล
+type voiceValidator interface {
+	ValidateVoices(ctx context.Context, voices []VoiceSettings) error
+}
+
+// engine ที่ต้องปิดการเชื่อมต่อเมื่อเลิกใช้งาน
+type closer interface {
+	Close() error
+}
+
+// ฟังก์ชันสร้าง engine
+type SynthesizerFactory func(ctx context.Context, cfg *Config) (Synthesizer, error)
+
+// registry ของ engine ที่รองรับ เลือกด้วยชื่อ
+var synthesizerRegistry = map[string]SynthesizerFactory{}
+
+// ลงทะเบียน engine ใหม่
+func registerSynthesizer(name string, factory SynthesizerFactory) {
+	if _, exists := synthesizerRegistry[name]; exists {
+		panic("ลงทะเบียน engine ซ้ำ: " + name)
+	}
+	synthesizerRegistry[name] = factory
+}
+
+// รายชื่อ engine ทั้งหมดที่ลงทะเบียนไว้
+func synthesizerNames() []string {
+	var names []string
+	for name := range synthesizerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// แยกรายชื่อ engine จากข้อความ เช่น "cloud,translate"
+func parseEngineChain(spec string) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := synthesizerRegistry[name]; !ok {
+			return nil, fmt.Errorf("ไม่รู้จัก engine %q (รองรับ: %s)", name, strings.Join(synthesizerNames(), ", "))
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("ไม่ได้ระบุ engine")
+	}
+	return names, nil
+}
+
+// สร้าง fallback chain ตามลำดับที่กำหนด ข้าม engine ที่สร้างไม่สำเร็จ
+func buildSynthesizerChain(ctx context.Context, cfg *Config, names []string) []Synthesizer {
+	var chain []Synthesizer
+	for _, name := range names {
+		engine, err := synthesizerRegistry[name](ctx, cfg)
+		if err != nil {
+			fmt.Printf("⚠️ ไม่สามารถใช้ engine %s: %s\n", name, err.Error())
+			continue
+		}
+		fmt.Printf("✅ ใช้ engine %s\n", name)
+		chain = append(chain, engine)
+	}
+	return chain
+}
+
+// ปิด engine ทั้งหมดใน chain
+func closeSynthesizerChain(chain []Synthesizer) {
+	for _, engine := range chain {
+		if c, ok := engine.(closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// ตรวจสอบเสียงทั้งหมดกับทุก engine ใน chain ที่รองรับการตรวจสอบ
+func validateChainVoices(ctx context.Context, chain []Synthesizer, voices []VoiceSettings) error {
+	for _, engine := range chain {
+		validator, ok := engine.(voiceValidator)
+		if !ok {
+			continue
+		}
+		err := validator.ValidateVoices(ctx, voices)
+		if err != nil {
+			return fmt.Errorf("%s: %v", engine.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// engine ปลอมที่บันทึกการเรียกและล้มเหลวตามที่กำหนด
+type fakeEngine struct {
+	name       string
+	chunkErr   error
+	synthErr   error
+	chunk      *AudioChunk
+	mu         sync.Mutex
+	calls      *[]string // ใช้ร่วมกันหลาย engine เพื่อตรวจลำดับ
+	closeCount int
+}
+
+func (f *fakeEngine) Name() string { return f.name }
+
+func (f *fakeEngine) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*f.calls = append(*f.calls, f.name+"."+call)
+}
+
+func (f *fakeEngine) Chunk(in PreparedText) ([]SynthesisRequest, error) {
+	f.record("Chunk")
+	if f.chunkErr != nil {
+		return nil, f.chunkErr
+	}
+	return []SynthesisRequest{{Text: in.Text}}, nil
+}
+
+func (f *fakeEngine) Synthesize(ctx context.Context, req SynthesisRequest) (*AudioChunk, error) {
+	f.record("Synthesize")
+	if f.synthErr != nil {
+		return nil, f.synthErr
+	}
+	return f.chunk, nil
+}
+
+func (f *fakeEngine) Close() error {
+	f.closeCount++
+	return nil
+}
+
+// ลงทะเบียน engine ชั่วคราวระหว่างการทดสอบ
+func registerTestSynthesizer(t *testing.T, name string, factory SynthesizerFactory) {
+	t.Helper()
+	registerSynthesizer(name, factory)
+	t.Cleanup(func() { delete(synthesizerRegistry, name) })
+}
+
+// ส่งงานเดียวให้ ttsWorker แล้วคืนผลลัพธ์
+func runTestWorker(t *testing.T, chain []Synthesizer) TTSResult {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := defaultConfig()
+	cfg.OutputDir = dir
+	cleaner, err := newTextCleaner("none", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.cleaner = cleaner
+
+	jobs := make(chan TTSJob, 1)
+	results := make(chan TTSResult, 1)
+	jobs <- TTSJob{ID: 1, FilePath: "chapters/001.txt", OutputPath: filepath.Join(dir, "001.mp3"), Text: "สวัสดีครับ"}
+	close(jobs)
+	ttsWorker(context.Background(), &cfg, nil, 1, jobs, results, chain)
+	result := <-results
+
+	if _, err := os.Stat(filepath.Join(dir, "temp_worker_1")); !os.IsNotExist(err) {
+		t.Errorf("worker temp folder left behind: %v", err)
+	}
+	return result
+}
+
+func TestTTSWorkerFallsBackToNextEngine(t *testing.T) {
+	var calls []string
+	first := &fakeEngine{name: "first", chunkErr: fmt.Errorf("แบ่งไม่ได้"), calls: &calls}
+	second := &fakeEngine{name: "second", synthErr: fmt.Errorf("quota หมด"), calls: &calls}
+
+	result := runTestWorker(t, []Synthesizer{first, second})
+	if result.Success || result.Engine != "" {
+		t.Fatalf("result = %+v, want failure", result)
+	}
+	// ข้อผิดพลาดของทุก engine ตามลำดับที่ลอง
+	if msg := result.Error.Error(); !strings.Contains(msg, "first: แบ่งไม่ได้") || !strings.Contains(msg, "second:") ||
+		strings.Index(msg, "first:") > strings.Index(msg, "second:") {
+		t.Errorf("error = %q, want both engine errors in chain order", msg)
+	}
+	if want := []string{"first.Chunk", "second.Chunk", "second.Synthesize"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestTTSWorkerUsesFallbackResult(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	// เสียงเงียบสั้นๆ ที่ ffmpeg อ่านได้
+	wav := filepath.Join(t.TempDir(), "silence.wav")
+	if out, err := exec.Command("ffmpeg", "-v", "error", "-f", "lavfi", "-i", "anullsrc=r=24000:cl=mono", "-t", "0.2", wav).CombinedOutput(); err != nil {
+		t.Fatalf("ffmpeg: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	first := &fakeEngine{name: "first", synthErr: fmt.Errorf("ล้มเหลว"), calls: &calls}
+	second := &fakeEngine{name: "second", chunk: &AudioChunk{Data: data, Format: "wav"}, calls: &calls}
+	result := runTestWorker(t, []Synthesizer{first, second})
+	if !result.Success || result.Engine != "second" || result.Size == 0 {
+		t.Errorf("result = %+v, want success from second", result)
+	}
+}
+
+func TestTTSWorkerEmptyChain(t *testing.T) {
+	result := runTestWorker(t, nil)
+	if result.Success || result.Error == nil || !strings.Contains(result.Error.Error(), "ไม่มี engine") {
+		t.Errorf("result = %+v, want no usable engine error", result)
+	}
+}
+
+func TestParseEngineChain(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr string
+	}{
+		{"cloud,translate", []string{"cloud", "translate"}, ""},
+		// ชื่อซ้ำและตัวพิมพ์ใหญ่
+		{" Translate , cloud,translate ", []string{"translate", "cloud"}, ""},
+		{"cloud,polly", nil, `ไม่รู้จัก engine "polly" (รองรับ: cloud, translate)`},
+		{" , ", nil, "ไม่ได้ระบุ engine"},
+	}
+	for _, tt := range tests {
+		got, err := parseEngineChain(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseEngineChain(%q) = %q, %v, want error %q", tt.spec, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseEngineChain(%q) = %q, %v, want %q", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestBuildSynthesizerChainSkipsUnusable(t *testing.T) {
+	var calls []string
+	working := &fakeEngine{name: "fake-working", calls: &calls}
+	registerTestSynthesizer(t, "fake-broken", func(ctx context.Context, cfg *Config) (Synthesizer, error) {
+		return nil, fmt.Errorf("ไม่มี credentials")
+	})
+	registerTestSynthesizer(t, "fake-working", func(ctx context.Context, cfg *Config) (Synthesizer, error) {
+		return working, nil
+	})
+
+	cfg := defaultConfig()
+	names, err := parseEngineChain("fake-broken,fake-working")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := buildSynthesizerChain(context.Background(), &cfg, names)
+	if len(chain) != 1 || chain[0] != working {
+		t.Fatalf("chain = %v, want only the working engine", chain)
+	}
+	closeSynthesizerChain(chain)
+	if working.closeCount != 1 {
+		t.Errorf("Close called %d times, want 1", working.closeCount)
+	}
+}
+
+func TestRunBatchNoUsableEngine(t *testing.T) {
+	registerTestSynthesizer(t, "fake-broken", func(ctx context.Context, cfg *Config) (Synthesizer, error) {
+		return nil, fmt.Errorf("ไม่มี credentials")
+	})
+
+	input := t.TempDir()
+	if err := os.WriteFile(filepath.Join(input, "001.txt"), []byte("สวัสดีครับ"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultConfig()
+	cfg.InputDir = input
+	cfg.OutputDir = t.TempDir()
+	cfg.Engines = "fake-broken"
+	cfg.CacheDir = t.TempDir()
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if code := runBatch(context.Background(), &cfg); code != 1 {
+		t.Errorf("runBatch = %d, want 1", code)
+	}
+}
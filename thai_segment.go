@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// พจนานุกรมคำไทยพื้นฐานที่ฝังมากับโปรแกรม (คำที่พบบ่อยราว 700 คำ)
+// คำที่ไม่รู้จักใช้ขอบเขตของกลุ่มพยัญชนะ สระและวรรณยุกต์แทน ดู isThaiClusterBoundary
+//
+//go:embed thai_words.txt
+var embeddedThaiWords string
+
+// พจนานุกรมสำหรับตัดคำไทยแบบ maximal matching
+type thaiDictionary struct {
+	root  *thaiTrieNode
+	words int
+}
+
+type thaiTrieNode struct {
+	children map[rune]*thaiTrieNode
+	word     bool
+}
+
+func newThaiDictionary() *thaiDictionary {
+	return &thaiDictionary{root: &thaiTrieNode{}}
+}
+
+// เพิ่มคำลงพจนานุกรม
+func (d *thaiDictionary) add(word string) {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return
+	}
+	node := d.root
+	for _, r := range word {
+		if node.children == nil {
+			node.children = map[rune]*thaiTrieNode{}
+		}
+		next, ok := node.children[r]
+		if !ok {
+			next = &thaiTrieNode{}
+			node.children[r] = next
+		}
+		node = next
+	}
+	if !node.word {
+		node.word = true
+		d.words++
+	}
+}
+
+// อ่านรายการคำ (หนึ่งคำต่อบรรทัด ข้ามบรรทัดว่างและบรรทัดที่ขึ้นต้นด้วย #)
+func (d *thaiDictionary) load(text string) {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d.add(line)
+	}
+}
+
+// พจนานุกรมที่ใช้ตัดคำ (โหลดจากไฟล์ที่ฝังไว้เมื่อใช้ครั้งแรก)
+var (
+	thaiDictOnce sync.Once
+	thaiDict     *thaiDictionary
+)
+
+func defaultThaiDictionary() *thaiDictionary {
+	thaiDictOnce.Do(func() {
+		thaiDict = newThaiDictionary()
+		thaiDict.load(embeddedThaiWords)
+	})
+	return thaiDict
+}
+
+// เพิ่มคำจากไฟล์พจนานุกรมของผู้ใช้ เช่น ชื่อตัวละครหรือคำเฉพาะของนิยาย
+func loadUserDictionary(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("ไม่สามารถอ่านพจนานุกรม %s: %v", path, err)
+	}
+	dict := defaultThaiDictionary()
+	before := dict.words
+	dict.load(string(data))
+	return dict.words - before, nil
+}
+
+// ตัวอักษรไทย (U+0E00 - U+0E7F)
+func isThaiRune(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+// สระหรือเครื่องหมายที่ต้องตามหลังพยัญชนะ (ขึ้นต้นคำไม่ได้)
+func isThaiFollowingMark(r rune) bool {
+	return r == 0x0E30 || // ะ
+		r == 0x0E31 || // ั
+		r == 0x0E32 || r == 0x0E33 || // า ำ
+		(r >= 0x0E34 && r <= 0x0E3A) || // ิ ี ึ ื ุ ู ฺ
+		r == 0x0E45 || r == 0x0E46 || // ๅ ๆ
+		(r >= 0x0E47 && r <= 0x0E4E) // ็ ่ ้ ๊ ๋ ์ ํ ๎
+}
+
+// สระหน้า เ แ โ ใ ไ (ต้องอยู่ติดกับพยัญชนะที่ตามมา)
+func isThaiLeadingVowel(r rune) bool {
+	return r >= 0x0E40 && r <= 0x0E44
+}
+
+// ตรวจสอบว่าแบ่งก่อนตำแหน่ง i ได้โดยไม่แยกพยัญชนะออกจากสระหรือวรรณยุกต์
+func isThaiClusterBoundary(runes []rune, i int) bool {
+	if i <= 0 || i >= len(runes) {
+		return true
+	}
+	if isThaiFollowingMark(runes[i]) || isThaiLeadingVowel(runes[i-1]) {
+		return false
+	}
+	return true
+}
+
+// ขอบเขตคำในข้อความ: boundaries[i] = true หมายถึงแบ่งก่อนตัวอักษรที่ i ได้
+func (d *thaiDictionary) boundaries(runes []rune) []bool {
+	boundaries := make([]bool, len(runes)+1)
+	boundaries[0] = true
+	boundaries[len(runes)] = true
+
+	i := 0
+	for i < len(runes) {
+		if !isThaiRune(runes[i]) {
+			// ข้อความที่ไม่ใช่ภาษาไทย: แบ่งได้ทุกตำแหน่งยกเว้นกลางคำหรือตัวเลข
+			if i > 0 && !(isWordRune(runes[i-1]) && isWordRune(runes[i])) {
+				boundaries[i] = true
+			}
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isThaiRune(runes[end]) {
+			end++
+		}
+		boundaries[i] = true
+		for _, b := range d.segment(runes[i:end]) {
+			boundaries[i+b] = true
+		}
+		i = end
+	}
+	return boundaries
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ส่วนหนึ่งของข้อความไทยที่ตัดแล้ว (ตำแหน่งเป็น index ของ rune)
+type thaiSegment struct {
+	start, end int
+	known      bool // เป็นคำในพจนานุกรม
+}
+
+// ตำแหน่งแบ่งภายในข้อความไทยล้วน (ไม่รวม 0 และ len)
+func (d *thaiDictionary) segment(run []rune) []int {
+	var cuts []int
+	for _, s := range d.segments(run) {
+		if s.start > 0 {
+			cuts = append(cuts, s.start)
+		}
+	}
+	return cuts
+}
+
+// ตัดคำข้อความไทยล้วนแบบ maximal matching
+// เลือกการตัดที่มีตัวอักษรที่ไม่รู้จักน้อยที่สุด แล้วจึงเลือกจำนวนคำน้อยที่สุด
+// ส่วนที่ไม่รู้จักที่อยู่ติดกันจะถูกรวมเป็นก้อนเดียว (ไม่แบ่งกลางชื่อเฉพาะ)
+func (d *thaiDictionary) segments(run []rune) []thaiSegment {
+	type state struct {
+		unknown int
+		words   int
+		prev    int
+		known   bool
+		ok      bool
+	}
+	n := len(run)
+	best := make([]state, n+1)
+	best[0] = state{ok: true, known: true}
+
+	better := func(a, b state) bool {
+		if !b.ok {
+			return true
+		}
+		if a.unknown != b.unknown {
+			return a.unknown < b.unknown
+		}
+		return a.words < b.words
+	}
+
+	for i := 0; i < n; i++ {
+		if !best[i].ok || !isThaiClusterBoundary(run, i) {
+			continue
+		}
+		cur := best[i]
+
+		// คำในพจนานุกรมที่เริ่มที่ตำแหน่ง i
+		node := d.root
+		for k := i; k < n; k++ {
+			node = node.children[run[k]]
+			if node == nil {
+				break
+			}
+			end := k + 1
+			if end < n && run[end] == 'ๆ' {
+				// ไม้ยมกอยู่กับคำที่ซ้ำเสมอ
+				end++
+			}
+			if node.word && isThaiClusterBoundary(run, end) {
+				next := state{unknown: cur.unknown, words: cur.words + 1, prev: i, known: true, ok: true}
+				if better(next, best[end]) {
+					best[end] = next
+				}
+			}
+		}
+
+		// ไม่รู้จัก: ข้ามไปหนึ่งกลุ่มตัวอักษร (พยัญชนะพร้อมสระและวรรณยุกต์)
+		k := i + 1
+		for k < n && !isThaiClusterBoundary(run, k) {
+			k++
+		}
+		next := state{unknown: cur.unknown + (k - i), words: cur.words + 1, prev: i, known: false, ok: true}
+		if better(next, best[k]) {
+			best[k] = next
+		}
+	}
+
+	// ย้อนหาเส้นทางที่ดีที่สุด แล้วรวมส่วนที่ไม่รู้จักที่อยู่ติดกัน
+	var reversed []thaiSegment
+	for pos := n; pos > 0; {
+		s := best[pos]
+		if len(reversed) > 0 && !s.known && !reversed[len(reversed)-1].known {
+			reversed[len(reversed)-1].start = s.prev
+		} else {
+			reversed = append(reversed, thaiSegment{start: s.prev, end: pos, known: s.known})
+		}
+		pos = s.prev
+	}
+	segments := make([]thaiSegment, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		segments = append(segments, reversed[i])
+	}
+	return segments
+}
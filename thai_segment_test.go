@@ -0,0 +1,155 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ประโยคไทยที่ตัดคำไว้ด้วย | (ทุกคำอยู่ใน thai_words.txt)
+var thaiSegmentCorpus = []string{
+	"เขา|เดิน|ออกไป|จาก|บ้าน|ทันที",
+	"ศิษย์|ของ|ปรมาจารย์|ฝึกฝน|วิชา|ดาบ|ทุก|วัน",
+	"ความรู้สึก|ของ|เธอ|เปลี่ยน|ไป|ตลอด|กาลเวลา",
+	"พวกเขา|ต้อง|ต่อสู้|กับ|ศัตรู|ที่|แข็งแกร่ง",
+	"ค่อยๆ|เดิน|เข้า|ไป|ใน|ถ้ำ|ที่|มืด",
+	"จักรพรรดิ|สั่ง|ให้|ทหาร|ปกครอง|อาณาจักร|อย่าง|เงียบ",
+	"ผู้อาวุโส|หัวเราะ|แล้ว|ตอบ|คำถาม|ของ|ศิษย์",
+}
+
+// ตำแหน่ง rune ของขอบเขตคำภายในประโยค (ไม่รวม 0 และความยาว)
+func corpusBoundaries(entry string) (string, map[int]bool) {
+	words := strings.Split(entry, "|")
+	cuts := map[int]bool{}
+	pos := 0
+	for _, word := range words[:len(words)-1] {
+		pos += len([]rune(word))
+		cuts[pos] = true
+	}
+	return strings.Join(words, ""), cuts
+}
+
+func TestThaiDictionarySegments(t *testing.T) {
+	dict := defaultThaiDictionary()
+	for _, entry := range thaiSegmentCorpus {
+		text, cuts := corpusBoundaries(entry)
+		var got []string
+		for _, s := range dict.segments([]rune(text)) {
+			if !s.known {
+				t.Errorf("%s: unknown segment %q", entry, string([]rune(text)[s.start:s.end]))
+			}
+			got = append(got, string([]rune(text)[s.start:s.end]))
+		}
+		if strings.Join(got, "|") != entry {
+			t.Errorf("segments = %s, want %s", strings.Join(got, "|"), entry)
+		}
+		if len(dict.segment([]rune(text))) != len(cuts) {
+			t.Errorf("%s: segment cuts = %v", entry, dict.segment([]rune(text)))
+		}
+	}
+}
+
+func TestFindBestBreakPointStaysOnWordBoundaries(t *testing.T) {
+	dict := defaultThaiDictionary()
+	for _, entry := range thaiSegmentCorpus {
+		text, cuts := corpusBoundaries(entry)
+		runes := []rune(text)
+		boundaries := dict.boundaries(runes)
+		firstWord := len([]rune(strings.Split(entry, "|")[0]))
+
+		// ทุกความยาวสูงสุดที่ยาวกว่าคำแรก ต้องแบ่งที่ขอบเขตคำเสมอ
+		for maxEnd := firstWord + 1; maxEnd < len(runes); maxEnd++ {
+			got := findBestBreakPoint(runes, boundaries, 0, maxEnd)
+			if !cuts[got] || got > maxEnd {
+				t.Errorf("%s: findBestBreakPoint(maxEnd=%d) = %d, inside a word", entry, maxEnd, got)
+			}
+		}
+	}
+}
+
+func TestSplitTextKeepsThaiWordsWhole(t *testing.T) {
+	for _, entry := range thaiSegmentCorpus {
+		text, _ := corpusBoundaries(entry)
+		words := map[string]bool{}
+		longest := 0
+		for _, word := range strings.Split(entry, "|") {
+			words[word] = true
+			longest = max(longest, len([]rune(word)))
+		}
+
+		for maxLen := longest; maxLen < len([]rune(text)); maxLen++ {
+			parts := splitText(text, maxLen)
+			if strings.Join(parts, "") != text {
+				t.Fatalf("%s: parts %q do not rebuild the text", entry, parts)
+			}
+			// ทุกส่วนต้องประกอบด้วยคำเต็มจากประโยคเท่านั้น
+			joined := "|" + entry + "|"
+			for _, part := range parts {
+				if len([]rune(part)) > maxLen {
+					t.Errorf("%s: part %q longer than %d", entry, part, maxLen)
+				}
+				if !containsWordSequence(joined, part, words) {
+					t.Errorf("%s (max %d): part %q cuts a word", entry, maxLen, part)
+				}
+			}
+		}
+	}
+}
+
+// ตรวจสอบว่า part คือคำที่ติดกันตั้งแต่หนึ่งคำขึ้นไปในประโยคที่ตัดคำแล้ว
+func containsWordSequence(joined, part string, words map[string]bool) bool {
+	for i := 0; i < len(joined); i++ {
+		if joined[i] != '|' {
+			continue
+		}
+		rest := joined[i+1:]
+		var sb strings.Builder
+		for _, word := range strings.Split(rest, "|") {
+			if !words[word] {
+				break
+			}
+			sb.WriteString(word)
+			if sb.String() == part {
+				return true
+			}
+			if sb.Len() > len(part) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+func TestThaiUnknownRunKeptTogether(t *testing.T) {
+	dict := newThaiDictionary()
+	dict.load("เดิน\nไป\n")
+
+	// ชื่อเฉพาะที่ไม่อยู่ในพจนานุกรมถูกรวมเป็นก้อนเดียว ไม่แบ่งกลางชื่อ
+	run := []rune("สมศักดิ์เดินไป")
+	got := dict.segment(run)
+	if want := []int{8, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("segment = %v, want %v", got, want)
+	}
+
+	// หลังเพิ่มชื่อในพจนานุกรมของผู้ใช้ก็ยังตัดที่ตำแหน่งเดิม
+	dict.add("สมศักดิ์")
+	for _, s := range dict.segments(run) {
+		if !s.known {
+			t.Errorf("segment %q still unknown", string(run[s.start:s.end]))
+		}
+	}
+}
+
+func TestThaiClusterBoundaryFallback(t *testing.T) {
+	// คำที่ไม่รู้จักทั้งหมดแบ่งได้เฉพาะระหว่างกลุ่มพยัญชนะกับสระหรือวรรณยุกต์
+	runes := []rune("เกี้ยวพาราสี")
+	for i := 1; i < len(runes); i++ {
+		ok := isThaiClusterBoundary(runes, i)
+		if isThaiFollowingMark(runes[i]) && ok {
+			t.Errorf("boundary before mark %q at %d", runes[i], i)
+		}
+		if isThaiLeadingVowel(runes[i-1]) && ok {
+			t.Errorf("boundary after leading vowel at %d", i)
+		}
+	}
+}
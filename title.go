@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ชื่อบทที่ตรวจพบในบรรทัดแรกของไฟล์
+type chapterTitle struct {
+	Number  int
+	Name    string // ชื่อบทหลังหมายเลข เช่น "การเดินทาง" (ว่างได้)
+	Display string // ชื่อบทสำหรับ metadata เช่น "บทที่ 12: การเดินทาง"
+}
+
+// ชื่อบทที่ยาวกว่านี้ถือว่าเป็นเนื้อหา
+const maxChapterTitleRunes = 120
+
+var (
+	// "บทที่ 12", "ตอนที่ ๓: ชื่อตอน", "Chapter 3 - The Fall"
+	numberedTitlePattern = regexp.MustCompile(`^(?:บทที่|ตอนที่|(?i:chapter))\s*([0-9๐-๙]+)\s*(?:[:.\-–—]\s*)?(.*)$`)
+	// บรรทัดที่มีแต่หมายเลข
+	bareNumberTitlePattern = regexp.MustCompile(`^([0-9๐-๙]+)$`)
+	// หัวข้อ markdown เช่น "# การเดินทาง"
+	markdownTitlePattern = regexp.MustCompile(`^#+\s*(.+?)\s*#*$`)
+)
+
+// หาชื่อบทจากบรรทัดแรกที่ไม่ว่าง คืนชื่อบทและข้อความที่เหลือ
+// number คือหมายเลขบทที่ใช้เมื่อหัวข้อไม่มีหมายเลข
+func detectChapterTitle(text string, number int) (chapterTitle, string, bool) {
+	first, rest, _ := strings.Cut(strings.TrimLeft(text, " \t\r\n\uFEFF"), "\n")
+	first = strings.TrimSpace(first)
+	if first == "" || utf8.RuneCountInString(first) > maxChapterTitleRunes {
+		return chapterTitle{}, text, false
+	}
+
+	heading := strings.TrimSpace(strings.Trim(first, "*"))
+	title := chapterTitle{Number: number}
+	unmarked := strings.TrimSpace(strings.Trim(heading, "#"))
+	if m := numberedTitlePattern.FindStringSubmatch(unmarked); m != nil {
+		title.Number, _ = strconv.Atoi(thaiDigitReplacer.Replace(m[1]))
+		title.Name = strings.TrimSpace(m[2])
+		title.Display = unmarked
+	} else if m := bareNumberTitlePattern.FindStringSubmatch(unmarked); m != nil {
+		title.Number, _ = strconv.Atoi(thaiDigitReplacer.Replace(m[1]))
+		title.Display = fmt.Sprintf("บทที่ %d", title.Number)
+	} else if m := markdownTitlePattern.FindStringSubmatch(heading); m != nil {
+		title.Name = m[1]
+		title.Display = m[1]
+	} else {
+		return chapterTitle{}, text, false
+	}
+	return title, rest, true
+}
+
+// ชื่อบทจากสารบัญของ EPUB หรือหัวข้อของ Markdown/HTML คืนชื่อบทและข้อความที่ตัดหัวข้อซ้ำกับชื่อบทออกแล้ว
+func tocChapterTitle(name, text string, number int) (chapterTitle, string) {
+	title, _, ok := detectChapterTitle(name, number)
+	if !ok {
+		title = chapterTitle{Number: number, Name: name, Display: name}
+	}
+
+	// เนื้อหามักขึ้นต้นด้วยหัวข้อเดียวกับสารบัญ
+	first, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if strings.Join(strings.Fields(stripEmphasis(first)), " ") == name {
+		return title, rest
+	}
+	if heading, rest, ok := detectChapterTitle(text, number); ok && heading.Display == title.Display {
+		return title, rest
+	}
+	return title, text
+}
+
+// ข้อความประกาศชื่อบทตามรูปแบบ
+// {number} = หมายเลขเป็นคำอ่าน, {n} = หมายเลขเป็นตัวเลข, {name} = ชื่อบท
+func (t chapterTitle) render(template string) string {
+	replacer := strings.NewReplacer(
+		"{number}", thaiNumber(strconv.Itoa(t.Number)),
+		"{n}", strconv.Itoa(t.Number),
+		"{name}", t.Name,
+	)
+	return strings.Join(strings.Fields(replacer.Replace(template)), " ")
+}
+
+// เสียงที่ใช้ประกาศชื่อบท (ใช้เสียงของบทหากไม่ได้กำหนด title_voice)
+func (c *Config) titleVoice(chapter VoiceSettings) VoiceSettings {
+	if c.TitleVoice == "" {
+		return chapter
+	}
+	return VoiceSettings{Name: c.TitleVoice, Language: chapter.Language}
+}
+
+// คำขอสังเคราะห์เสียงประกาศชื่อบทตามด้วยช่วงเงียบ
+// engine ที่สร้างช่วงเงียบเองได้จะได้ส่วนเงียบแยก ส่วน engine อื่นใช้ SSML <break>
+func titleRequests(cfg *Config, engine Synthesizer, job TTSJob) ([]SynthesisRequest, error) {
+	pause := pauseDuration(cfg.TitlePause)
+	voice := cfg.titleVoice(job.Voice)
+
+	var reqs []SynthesisRequest
+	var err error
+	if _, ok := engine.(silenceGenerator); ok || pause <= 0 {
+		reqs, err = engine.Chunk(PreparedText{Text: job.Announcement})
+		if err == nil && pause > 0 {
+			reqs = append(reqs, SynthesisRequest{Pause: pause})
+		}
+	} else {
+		doc := "<speak>" + escapeSSML(job.Announcement) + ssmlBreak(cfg.TitlePause) + "</speak>"
+		reqs, err = engine.Chunk(PreparedText{Text: doc, SSML: true})
+	}
+	if err != nil {
+		return nil, err
+	}
+	// คำบรรยายแสดงชื่อบทตามต้นฉบับ ไม่ใช่คำอ่านของหมายเลขบท
+	display := job.Title
+	if display == "" {
+		display = job.Announcement
+	}
+	for i := range reqs {
+		reqs[i].Voice = voice
+		if reqs[i].Pause == 0 {
+			reqs[i].Display = display
+		}
+	}
+	return reqs, nil
+}
+
+// ข้อความประกาศชื่อบทที่ผ่านกฎทำความสะอาดและ lexicon แล้ว
+func (c *Config) announcement(title chapterTitle, lex *lexicon) string {
+	text := title.render(c.TitleTemplate)
+	if cleaned, ok := c.cleaner.cleanLine(text); ok {
+		text = cleaned
+	}
+	if lex != nil {
+		text = lex.apply(text)
+	}
+	return text
+}
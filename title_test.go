@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectChapterTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		want  chapterTitle
+		rest  string
+		found bool
+	}{
+		{"numbered", "บทที่ 12: การเดินทาง\nเนื้อหา", chapterTitle{12, "การเดินทาง", "บทที่ 12: การเดินทาง"}, "เนื้อหา", true},
+		{"thai digits", "\uFEFF\n  ตอนที่ ๓ ศิษย์ใหม่\nเนื้อหา", chapterTitle{3, "ศิษย์ใหม่", "ตอนที่ ๓ ศิษย์ใหม่"}, "เนื้อหา", true},
+		{"english", "Chapter 3 - The Fall\nเนื้อหา", chapterTitle{3, "The Fall", "Chapter 3 - The Fall"}, "เนื้อหา", true},
+		{"number only", "**บทที่ 5**\nเนื้อหา", chapterTitle{5, "", "บทที่ 5"}, "เนื้อหา", true},
+		{"bare number", "## 7\nเนื้อหา", chapterTitle{7, "", "บทที่ 7"}, "เนื้อหา", true},
+		// หัวข้อที่ไม่มีหมายเลขใช้หมายเลขจากชื่อไฟล์
+		{"markdown", "# การเดินทาง\nเนื้อหา", chapterTitle{9, "การเดินทาง", "การเดินทาง"}, "เนื้อหา", true},
+		{"heading only", "บทที่ 1", chapterTitle{1, "", "บทที่ 1"}, "", true},
+		// ไม่มีหัวข้อ: ข้อความไม่เปลี่ยน
+		{"prose", "เขาเดินออกไป\nแล้วหยุด", chapterTitle{}, "เขาเดินออกไป\nแล้วหยุด", false},
+		{"too long", "บทที่ 1 " + strings.Repeat("ก", maxChapterTitleRunes) + "\nเนื้อหา", chapterTitle{}, "บทที่ 1 " + strings.Repeat("ก", maxChapterTitleRunes) + "\nเนื้อหา", false},
+		{"empty", "\n\n", chapterTitle{}, "\n\n", false},
+	}
+	for _, tt := range tests {
+		title, rest, ok := detectChapterTitle(tt.text, 9)
+		if ok != tt.found || title != tt.want || rest != tt.rest {
+			t.Errorf("%s: detectChapterTitle = %+v, %q, %v, want %+v, %q, %v", tt.name, title, rest, ok, tt.want, tt.rest, tt.found)
+		}
+	}
+}
+
+func TestTOCChapterTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		toc  string
+		text string
+		want chapterTitle
+		rest string
+	}{
+		// หัวข้อซ้ำกับสารบัญถูกตัดออก
+		{"same heading", "บทที่ 3 ดาบ", "บทที่ 3 ดาบ\nเนื้อหา", chapterTitle{3, "ดาบ", "บทที่ 3 ดาบ"}, "เนื้อหา"},
+		{"emphasized heading", "บทที่ 3 ดาบ", string(emphasisStart) + "บทที่ 3" + string(emphasisEnd) + "  ดาบ\nเนื้อหา", chapterTitle{3, "ดาบ", "บทที่ 3 ดาบ"}, "เนื้อหา"},
+		// สารบัญมีชื่อบทแต่หัวข้อในเนื้อหาเขียนต่างกันเล็กน้อย
+		{"same title", "บทที่ 3: ดาบ", "บทที่ 3: ดาบ \n\nเนื้อหา", chapterTitle{3, "ดาบ", "บทที่ 3: ดาบ"}, "\nเนื้อหา"},
+		// ชื่อในสารบัญที่ไม่มีหมายเลขใช้หมายเลขของบท
+		{"name only", "บทนำ", "บทนำ\nเนื้อหา", chapterTitle{4, "บทนำ", "บทนำ"}, "เนื้อหา"},
+		// สารบัญใช้ชื่อแทนหัวข้อในเนื้อหาที่ต่างกัน เนื้อหาไม่ถูกตัด
+		{"different heading", "Chapter 3", "บทที่ 3\nเนื้อหา", chapterTitle{3, "", "Chapter 3"}, "บทที่ 3\nเนื้อหา"},
+		{"no heading", "บทที่ 3 ดาบ", "เนื้อหา", chapterTitle{3, "ดาบ", "บทที่ 3 ดาบ"}, "เนื้อหา"},
+	}
+	for _, tt := range tests {
+		title, rest := tocChapterTitle(tt.toc, tt.text, 4)
+		if title != tt.want || rest != tt.rest {
+			t.Errorf("%s: tocChapterTitle = %+v, %q, want %+v, %q", tt.name, title, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestChapterTitleRender(t *testing.T) {
+	title := chapterTitle{Number: 12, Name: "การเดินทาง"}
+	tests := []struct {
+		template string
+		title    chapterTitle
+		want     string
+	}{
+		{"บทที่ {number} {name}", title, "บทที่ สิบสอง การเดินทาง"},
+		{"ตอน {n}: {name}", title, "ตอน 12: การเดินทาง"},
+		// ชื่อบทว่างไม่เหลือช่องว่างซ้ำ
+		{"บทที่ {number}  {name} ", chapterTitle{Number: 1}, "บทที่ หนึ่ง"},
+	}
+	for _, tt := range tests {
+		if got := tt.title.render(tt.template); got != tt.want {
+			t.Errorf("render(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestConfigAnnouncement(t *testing.T) {
+	cfg := testSubtitleConfig(t)
+	lex := testLexicon(t, "Qi = ชี่\n")
+
+	// ผ่านกฎทำความสะอาดและ lexicon เหมือนเนื้อหา
+	cfg.TitleTemplate = "บทที่ {number} {name}"
+	if got, want := cfg.announcement(chapterTitle{Number: 12, Name: "พลัง Qi & ดาบ"}, lex), "บทที่ สิบสอง พลัง ชี่ ดาบ"; got != want {
+		t.Errorf("announcement = %q, want %q", got, want)
+	}
+	if got, want := cfg.announcement(chapterTitle{Number: 2, Name: "Qi"}, nil), "บทที่ สอง Qi"; got != want {
+		t.Errorf("announcement without lexicon = %q, want %q", got, want)
+	}
+
+	// ชุดกฎ novel ลบบรรทัด "บทที่ 12" แต่ประกาศชื่อบทต้องไม่หายไป
+	cfg.TitleTemplate = "บทที่ {n}"
+	if got, want := cfg.announcement(chapterTitle{Number: 12}, lex), "บทที่ 12"; got != want {
+		t.Errorf("announcement of a deleted line = %q, want %q", got, want)
+	}
+}
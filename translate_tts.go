@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	registerSynthesizer("translate", func(ctx context.Context, cfg *Config) (Synthesizer, error) {
+		return newTranslateTTS(cfg), nil
+	})
+}
+
+// endpoint ของ Google Translate TTS
+const translateTTSEndpoint = "https://translate.google.com/translate_tts"
+
+// Google Translate TTS (ไม่ต้องตั้งค่า แต่จำกัดความยาวต่อคำขอ)
+type translateTTS struct {
+	client    *http.Client
+	endpoint  string // แทนที่ได้ด้วย httptest server ในการทดสอบ
+	chunkSize int
+	pauses    documentPauses // ช่วงเงียบที่แทรกระหว่างย่อหน้าและประโยคตอนรวมไฟล์
+	retry     RetryPolicy
+	limiter   *rateLimiter // ใช้ร่วมกันทุก worker
+
+	silenceMu sync.Mutex
+	silences  map[time.Duration][]byte // ช่วงเงียบที่สร้างแล้ว แยกตามความยาว
+}
+
+func newTranslateTTS(cfg *Config) *translateTTS {
+	return &translateTTS{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		endpoint:  translateTTSEndpoint,
+		chunkSize: cfg.ChunkSize,
+		pauses:    cfg.documentPauses(),
+		retry:     cfg.retryPolicy(),
+		limiter:   newRateLimiter("translate", cfg.TranslateRPS, cfg.TranslateBurst),
+	}
+}
+
+// แปลงรหัสภาษาแบบ BCP-47 เป็นรหัสที่ Translate ใช้ เช่น "th-TH" เป็น "th"
+func translateLanguageCode(language string) string {
+	lang := strings.ToLower(language)
+	if strings.HasPrefix(lang, "cmn") {
+		// รหัสภาษาจีนกลางของ Cloud TTS
+		return "zh-CN"
+	}
+	if strings.HasPrefix(lang, "zh") {
+		// ภาษาจีนต้องระบุภูมิภาค เช่น zh-CN, zh-TW
+		return language
+	}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		return lang[:i]
+	}
+	return lang
+}
+
+func (t *translateTTS) Name() string {
+	return "translate"
+}
+
+// แบ่งข้อความเป็นส่วนย่อยโดยไม่ข้ามย่อหน้า แล้วแทรกช่วงเงียบระหว่างย่อหน้าและประโยค
+// (ไม่ข้ามประโยคด้วยหากกำหนดช่วงเงียบระหว่างประโยค)
+func (t *translateTTS) Chunk(in PreparedText) ([]SynthesisRequest, error) {
+	var doc textDocument
+	if in.SSML {
+		// Translate TTS ไม่รองรับ SSML จึงอ่านเฉพาะข้อความ
+		doc = ssmlToDocument(in.Text)
+	} else {
+		doc = parseTextDocument(in.Text)
+	}
+	paragraphPause := pauseDuration(t.pauses.Paragraph)
+	sentencePause := pauseDuration(t.pauses.Sentence)
+
+	var reqs []SynthesisRequest
+	for i, paragraph := range doc.Paragraphs {
+		if i > 0 && paragraphPause > 0 {
+			reqs = append(reqs, SynthesisRequest{Pause: paragraphPause})
+		}
+		if sentencePause <= 0 {
+			for _, part := range splitText(paragraph.String(), t.chunkSize) {
+				reqs = append(reqs, SynthesisRequest{Text: part})
+			}
+			continue
+		}
+		for j, sentence := range paragraph.Sentences {
+			if j > 0 {
+				reqs = append(reqs, SynthesisRequest{Pause: sentencePause})
+			}
+			for _, part := range splitText(sentence, t.chunkSize) {
+				reqs = append(reqs, SynthesisRequest{Text: part})
+			}
+		}
+	}
+	return reqs, nil
+}
+
+// เพิ่ม volume ของเสียง Translate TTS ที่เบากว่า engine อื่น
+func (t *translateTTS) EnhanceFilters(sampleRate int) []string {
+	return []string{"volume=1.2"}
+}
+
+// การตั้งค่าเสียงสำหรับ cache key
+func (t *translateTTS) AudioConfig() string {
+	return "mp3"
+}
+
+// Translate TTS เลือกเสียงจากรหัสภาษาเท่านั้น ชื่อเสียงและเพศไม่มีผล
+func (t *translateTTS) VoiceKey(voice VoiceSettings) []string {
+	return []string{translateLanguageCode(voice.Language)}
+}
+
+func (t *translateTTS) Synthesize(ctx context.Context, req SynthesisRequest) (*AudioChunk, error) {
+	var chunk *AudioChunk
+	err := t.retry.do(ctx, func(attempt int) error {
+		var err error
+		chunk, err = t.fetch(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// ดาวน์โหลดเสียงหนึ่งครั้ง ข้อผิดพลาดชั่วคราวจะถูกทำเครื่องหมายให้ลองใหม่
+func (t *translateTTS) fetch(ctx context.Context, req SynthesisRequest) (*AudioChunk, error) {
+	// รอคิวของ limiter ที่ใช้ร่วมกันทุก worker เพื่อไม่ให้ถูก rate limit
+	err := t.limiter.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// เข้ารหัส URL
+	encodedText := url.QueryEscape(req.Text)
+	ttsURL := fmt.Sprintf("%s?ie=UTF-8&tl=%s&client=tw-ob&q=%s", t.endpoint, url.QueryEscape(translateLanguageCode(req.Voice.Language)), encodedText)
+
+	// สร้าง HTTP request พร้อม headers
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", ttsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ไม่สามารถสร้าง request: %v", err)
+	}
+
+	// เพิ่ม headers ที่จำเป็น
+	httpReq.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	httpReq.Header.Set("Referer", "https://translate.google.com/")
+
+	// ส่ง request
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, retryable(fmt.Errorf("ไม่สามารถดาวน์โหลดเสียง: %v", err), 0)
+	}
+	defer resp.Body.Close()
+
+	// ตรวจสอบ status code (429/503 อาจมี Retry-After บอกเวลารอ)
+	if resp.StatusCode != 200 {
+		err := fmt.Errorf("ได้รับ status code %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			t.limiter.Throttle()
+		}
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, retryable(err, parseRetryAfter(resp.Header, time.Now()))
+		}
+		return nil, err
+	}
+
+	// อ่านข้อมูลเสียง
+	audioData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, retryable(fmt.Errorf("ไม่สามารถอ่านข้อมูลเสียง: %v", err), 0)
+	}
+
+	// ตรวจสอบว่าได้ไฟล์เสียงจริงๆ (หน้า HTML มักเป็นหน้า captcha เมื่อถูกจำกัดความถี่)
+	if len(audioData) < 1000 || strings.Contains(string(audioData[:100]), "<html") {
+		t.limiter.Throttle()
+		return nil, retryable(fmt.Errorf("ได้รับข้อมูลที่ไม่ใช่เสียง (%d bytes)", len(audioData)), 0)
+	}
+
+	t.limiter.Success()
+	return &AudioChunk{Data: audioData, Format: "mp3"}, nil
+}
+
+// สร้างช่วงเงียบเป็น MP3 รูปแบบเดียวกับเสียงของ Translate TTS (24kHz mono)
+// เพื่อให้รวมไฟล์ด้วย concat ได้โดยไม่ต้องแปลงรูปแบบ
+func (t *translateTTS) Silence(ctx context.Context, d time.Duration) (*AudioChunk, error) {
+	t.silenceMu.Lock()
+	defer t.silenceMu.Unlock()
+	if data, ok := t.silences[d]; ok {
+		return &AudioChunk{Data: data, Format: "mp3"}, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-f", "lavfi",
+		"-i", "anullsrc=r=24000:cl=mono",
+		"-t", fmt.Sprintf("%.3f", d.Seconds()),
+		"-c:a", "libmp3lame",
+		"-b:a", "32k",
+		"-f", "mp3",
+		"pipe:1")
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ไม่สามารถสร้างช่วงเงียบ: %v", err)
+	}
+	if t.silences == nil {
+		t.silences = map[time.Duration][]byte{}
+	}
+	t.silences[d] = data
+	return &AudioChunk{Data: data, Format: "mp3"}, nil
+}